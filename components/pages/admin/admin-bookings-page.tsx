@@ -9,7 +9,6 @@ import { Button } from '@/components/ui/button';
 import { Input } from '@/components/ui/input';
 import { Label } from '@/components/ui/label';
 import { Badge } from '@/components/ui/badge';
-import { callExtraStore } from '@dootask/tools';
 import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from '@/components/ui/select';
 import { 
   Table, 
@@ -123,10 +122,12 @@ export default function AdminBookingsPage() {
     Confirm({
       title: '导出预定记录',
       message: '确定要导出预定记录吗？',
-      onConfirm: () => {
-        const url = exportApi.exportBookings(params);
-        //window.open(url, '_blank');
-        callExtraStore("downUrl", url)
+      onConfirm: async () => {
+        try {
+          await exportApi.exportBookings(params);
+        } catch (error) {
+          console.error('导出预定记录失败:', error);
+        }
       },
     });
   };
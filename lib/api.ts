@@ -3,14 +3,26 @@ import { getUserInfo } from '@dootask/tools';
 
 const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || 'https://lan-dootask.keli.vip/apps/roomly/api';
 
-// 基础API调用函数
+// 获取当前用户的 DooTask token
+export async function getAuthToken(): Promise<string> {
+  try {
+    const userInfo = await getUserInfo();
+    return userInfo?.token || '';
+  } catch {
+    return localStorage.getItem('token') || '';
+  }
+}
+
+// 基础API调用函数，所有请求均携带登录 token
 async function apiCall<T>(endpoint: string, options: RequestInit = {}): Promise<T> {
+  const token = await getAuthToken();
   const response = await fetch(`${API_BASE_URL}${endpoint}`, {
+    ...options,
     headers: {
       'Content-Type': 'application/json',
+      ...(token ? { Authorization: `Bearer ${token}` } : {}),
       ...options.headers,
     },
-    ...options,
   });
 
   if (!response.ok) {
@@ -20,6 +32,35 @@ async function apiCall<T>(endpoint: string, options: RequestInit = {}): Promise<
   return response.json();
 }
 
+// 携带登录 token 下载文件并保存到本地，文件名由调用方指定（跨域时读取不到 Content-Disposition）
+async function downloadFile(endpoint: string, filename: string): Promise<void> {
+  const token = await getAuthToken();
+  const response = await fetch(`${API_BASE_URL}${endpoint}`, {
+    headers: token ? { Authorization: `Bearer ${token}` } : {},
+  });
+
+  if (!response.ok) {
+    throw new Error(`Download failed: ${response.statusText}`);
+  }
+
+  const url = URL.createObjectURL(await response.blob());
+  const link = document.createElement('a');
+  link.href = url;
+  link.download = filename;
+  document.body.appendChild(link);
+  link.click();
+  link.remove();
+  // 等浏览器开始下载后再释放
+  setTimeout(() => URL.revokeObjectURL(url), 1000);
+}
+
+// 导出文件名中的时间，格式与服务端一致，如 20250106_093000
+function exportTimestamp(): string {
+  const now = new Date();
+  const pad = (value: number) => value.toString().padStart(2, '0');
+  return `${now.getFullYear()}${pad(now.getMonth() + 1)}${pad(now.getDate())}_${pad(now.getHours())}${pad(now.getMinutes())}${pad(now.getSeconds())}`;
+}
+
 // 会员相关API
 export const memberApi = {
   // 获取所有会员（支持分页、搜索、角色过滤）
@@ -36,6 +77,9 @@ export const memberApi = {
     return apiCall<{ data: Member[]; total: number }>(url);
   },
   
+  // 获取当前登录会员（不存在时由后端自动创建）
+  me: () => apiCall<Member>('/members/me'),
+
   // 获取单个会员
   get: (id: number) => apiCall<Member>(`/members/${id}`),
 
@@ -218,7 +262,7 @@ export const exportApi = {
       }
     });
     
-    return `${API_BASE_URL}/export/bookings?${queryParams.toString()}`;
+    return downloadFile(`/export/bookings?${queryParams.toString()}`, `预订记录_${exportTimestamp()}.xlsx`);
   },
   
   // 导出会议室使用统计
@@ -233,7 +277,7 @@ export const exportApi = {
       }
     });
     
-    return `${API_BASE_URL}/export/room-usage?${queryParams.toString()}`;
+    return downloadFile(`/export/room-usage?${queryParams.toString()}`, `会议室使用统计_${exportTimestamp()}.xlsx`);
   },
 }; 
 
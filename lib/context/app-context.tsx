@@ -42,24 +42,8 @@ export function AppProvider({ children }: { children: React.ReactNode }) {
         throw new Error('用户信息不存在');
       }
 
-      // 获取会员信息
-      let member: Member | null = null;
-      try {
-        member = await memberApi.getForDootaskId(userInfo.userid);
-        // 更新会员信息
-        member = await memberApi.update(member.id, {
-          name: userInfo.nickname,
-          is_admin: userInfo.identity.includes('admin'),
-        });
-      } catch {
-        // 会员不存在，创建会员
-        member = await memberApi.create({
-          dootask_id: userInfo.userid,
-          name: userInfo.nickname,
-          is_admin: userInfo.identity.includes('admin'),
-          is_room_admin: false,
-        });
-      }
+      // 获取会员信息（后端根据 token 自动创建会员并同步昵称和管理员身份）
+      const member: Member = await memberApi.me();
 
       // 设置当前会员
       setCurrentMember(member); 
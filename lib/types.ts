@@ -9,16 +9,150 @@ export interface Member {
   updated_at: string;
 }
 
+export interface MemberPreference {
+  id: number;
+  member_id: number;
+  reminder_minutes: number; // 会议开始前多少分钟提醒，0 表示不提醒
+  channels: string; // 接收通知的渠道，逗号分隔：dootask、email、webhook
+  email: string;
+  webhook_url: string;
+  locale: 'zh-CN' | 'en-US'; // 通知消息的语言
+  daily_digest: boolean; // 会议室管理员每天接收次日预定汇总
+  created_at: string;
+  updated_at: string;
+}
+
+export interface MessageTemplate {
+  event: string;
+  audience: 'attendee' | 'organizer' | 'admin';
+  locale: 'zh-CN' | 'en-US';
+  body: string; // Go text/template 模板
+  customized: boolean; // 管理员修改过，否则为内置模板
+  updated_at?: string | null;
+}
+
+export interface MessagePreview {
+  subject: string;
+  text: string;
+}
+
+export interface Notification {
+  id: number;
+  userid: number;
+  audience: 'attendee' | 'organizer' | 'admin';
+  channel: 'dootask' | 'email' | 'webhook';
+  msg_type: string;
+  payload: string; // 消息参数 JSON
+  status: 'pending' | 'sent' | 'dead';
+  attempts: number;
+  next_attempt_at: string;
+  last_error: string;
+  sent_at?: string | null;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface CalendarFeed {
+  id: number;
+  token: string;
+  kind: 'member' | 'room' | 'booking';
+  member_id: number;
+  room_id?: number | null;
+  booking_id?: number | null;
+  last_accessed_at?: string | null;
+  created_at: string;
+  updated_at: string;
+  room?: Room;
+  url: string;
+  webcal_url: string; // 在日历客户端中订阅
+}
+
+export type WebhookEvent = 'booking.created' | 'booking.updated' | 'booking.cancelled' | 'booking.expired' | 'booking.no_show';
+
+export interface WebhookSubscription {
+  id: number;
+  name: string;
+  url: string;
+  events: string; // 逗号分隔，为空表示全部事件
+  secret: string;
+  is_active: boolean;
+  created_by: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface WebhookDelivery {
+  id: number;
+  subscription_id: number;
+  event: WebhookEvent | 'ping';
+  booking_id: number;
+  payload: string; // 请求体 JSON
+  status: 'pending' | 'sent' | 'dead';
+  attempts: number;
+  next_attempt_at: string;
+  response_status: number;
+  response_body: string;
+  last_error: string;
+  delivered_at?: string | null;
+  created_at: string;
+  updated_at: string;
+}
+
 export interface Room {
   id: number;
   name: string;
   description: string;
   capacity: number;
   is_open: boolean;
+  slot_minutes: number; // 时间段长度: 15, 30, 60
+  min_duration: number; // 最短预定时长（分钟），0 表示不限
+  max_duration: number; // 最长预定时长（分钟），0 表示不限
+  holiday_policy: 'allow' | 'warn' | 'reject'; // 非工作日预定策略
+  capacity_policy: 'warn' | 'reject' | 'override'; // 超员策略，override 需会议室管理员确认
+  check_in_grace: number; // 签到宽限时间（分钟），0 表示不要求签到
+  requires_approval: boolean; // 预定是否需要审批
+  building: string; // 楼栋
+  floor: string; // 楼层
+  area: string; // 区域
+  business_hours?: RoomBusinessHour[];
+  amenities?: Amenity[];
+  photos?: RoomPhoto[];
+  created_at: string;
+  updated_at: string;
+}
+
+export interface RoomBusinessHour {
+  weekday: number; // 0 表示周日
+  open: string;
+  close: string;
+}
+
+export interface Amenity {
+  id: number;
+  code: string; // projector, video_conference, whiteboard, phone, accessible 等
+  name: string;
   created_at: string;
   updated_at: string;
 }
 
+export interface RoomPhoto {
+  id: number;
+  room_id: number;
+  url: string;
+  caption: string;
+  sort_order: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface RoomLocation {
+  building: string;
+  floors: {
+    floor: string;
+    areas: string[];
+  }[];
+}
+
 export interface Booking {
   id: number;
   room_id: number;
@@ -27,8 +161,13 @@ export interface Booking {
   start_time: string;
   end_time: string;
   reason: string;
+  is_private?: boolean; // 私密会议
   cancel_reason?: string; // 取消理由
-  status: 'active' | 'cancelled' | 'expired';
+  status: 'pending' | 'active' | 'rejected' | 'cancelled' | 'expired' | 'no_show';
+  checked_in_at?: string | null; // 签到时间
+  reviewed_by?: number | null; // 审批人
+  reviewed_at?: string | null; // 审批时间
+  review_reason?: string; // 审批意见（拒绝理由）
   created_at: string;
   updated_at: string;
   room: Room;
@@ -45,11 +184,91 @@ export interface TimeSlot {
   start: string;
   end: string;
   is_booked: boolean;
+  is_blocked?: boolean; // 会议室关闭
+  is_pending?: boolean; // 待审批的预定占用
 }
 
 export interface AvailableSlots {
   date: string;
   time_slots: TimeSlot[];
+  is_holiday?: boolean;
+  holiday_name?: string;
+}
+
+export interface WaitlistEntry {
+  id: number;
+  room_id: number;
+  member_id: number;
+  date: string;
+  start_time: string;
+  end_time: string;
+  reason: string;
+  auto_book: boolean;
+  status: 'waiting' | 'offered' | 'booked' | 'withdrawn' | 'expired';
+  offer_expires_at?: string | null; // 认领截止时间
+  booking_id?: number | null;
+  room: Room;
+  booking_users: BookingUser[];
+}
+
+export interface CalendarDay {
+  id: number;
+  date: string;
+  kind: 'holiday' | 'workday';
+  name: string;
+}
+
+export interface QuotaPolicy {
+  id: number;
+  name: string;
+  room_id: number | null; // 为空时适用于所有会议室
+  applies_to: 'member' | 'admin';
+  max_active_bookings: number; // 0 表示不限
+  max_weekly_minutes: number;
+  max_duration: number;
+  min_lead_minutes: number;
+  room?: Room;
+}
+
+export interface FreeWindow {
+  date: string;
+  start: string;
+  end: string;
+  duration: number; // 分钟
+}
+
+export interface RoomSearchResult {
+  room: Room;
+  capacity_fit: number; // 容量超出所需人数的数量，越小越合适
+  windows: FreeWindow[];
+}
+
+export interface GridBooking {
+  id: number;
+  status: Booking['status'];
+  start_time: string;
+  end_time: string;
+  member_id: number;
+  member_name: string;
+  reason: string; // 私密会议对非参会人员为空
+  is_private: boolean;
+  booking_users: BookingUser[];
+}
+
+export interface GridCell extends TimeSlot {
+  booking?: GridBooking;
+}
+
+export interface GridDay {
+  date: string;
+  is_holiday?: boolean;
+  holiday_name?: string;
+  slots: GridCell[];
+}
+
+export interface GridRow {
+  room: Room;
+  days: GridDay[];
 }
 
 export interface BookingRequest {
@@ -58,7 +277,36 @@ export interface BookingRequest {
   date: string;
   time_slots: string[];
   reason: string;
+  is_private?: boolean;
   booking_users: BookingUser[];
+  capacity_override?: boolean; // 会议室管理员确认超员
+  block_own_conflict?: boolean; // 预定人自己同一时间已有会议时拒绝预定
+}
+
+export interface AttendeeConflict {
+  userid: number;
+  nickname: string;
+  booking_id: number;
+  room_id: number;
+  room_name: string;
+  date: string;
+  start_time: string;
+  end_time: string;
+}
+
+export interface BusyTime {
+  booking_id: number;
+  room_id: number;
+  room_name: string;
+  date: string;
+  start_time: string;
+  end_time: string;
+  status: Booking['status'];
+}
+
+export interface UserBusyTimes {
+  userid: number;
+  busy: BusyTime[];
 }
 
 // 录音相关类型
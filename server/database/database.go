@@ -1,6 +1,7 @@
 package database
 
 import (
+	"fmt"
 	"log"
 	"os"
 
@@ -8,6 +9,7 @@ import (
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var DB *gorm.DB
@@ -15,21 +17,68 @@ var DB *gorm.DB
 // 初始化数据库连接
 func InitDB() {
 	os.MkdirAll("db", 0755)
+	if err := Open("db/roomly.db"); err != nil {
+		log.Fatal(err)
+	}
+}
 
+// Open 打开 path 处的数据库，迁移结构并创建初始数据；测试使用临时目录中的数据库
+func Open(path string) error {
 	var err error
-	DB, err = gorm.Open(sqlite.Open("db/roomly.db"), &gorm.Config{})
+	// 等待锁而不是立即报错，事务开始即获取写锁，保证并发写入串行执行
+	DB, err = gorm.Open(sqlite.Open(path+"?_busy_timeout=5000&_txlock=immediate"), &gorm.Config{
+		TranslateError: true,
+	})
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		return fmt.Errorf("Failed to connect to database: %w", err)
 	}
 
 	// 自动迁移数据库结构
-	err = DB.AutoMigrate(&models.Member{}, &models.Room{}, &models.Booking{}, &models.BookingUser{})
+	err = DB.AutoMigrate(&models.Member{}, &models.Room{}, &models.Booking{}, &models.BookingUser{}, &models.BookingSeries{}, &models.BookingSeriesUser{}, &models.BookingChange{}, &models.BookingSlot{}, &models.RoomBusinessHour{}, &models.RoomBlackout{}, &models.CalendarDay{}, &models.WaitlistEntry{}, &models.WaitlistUser{}, &models.QuotaPolicy{}, &models.Amenity{}, &models.RoomPhoto{}, &models.MemberPreference{}, &models.BookingReminder{}, &models.Notification{}, &models.MessageTemplate{}, &models.WebhookSubscription{}, &models.WebhookDelivery{}, &models.CalendarFeed{})
 	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+		return fmt.Errorf("Failed to migrate database: %w", err)
 	}
 
+	// 迁移旧的全局会议室管理员
+	migrateRoomAdmins()
+
+	// 统一结束时间的零点表示
+	normalizeEndOfDay()
+
+	// 补齐有效预定的时间段占用记录
+	backfillBookingSlots()
+
 	// 创建初始数据
 	seedData()
+	return nil
+}
+
+// 旧版本的会议室管理员是全局标记，首次升级时将其分配为所有会议室的管理员
+func migrateRoomAdmins() {
+	var assignmentCount int64
+	DB.Table("room_admins").Count(&assignmentCount)
+	if assignmentCount > 0 {
+		return
+	}
+
+	var admins []models.Member
+	DB.Where("is_room_admin = ?", true).Find(&admins)
+	if len(admins) == 0 {
+		return
+	}
+	var rooms []models.Room
+	DB.Find(&rooms)
+	for i := range admins {
+		if err := DB.Model(&admins[i]).Association("ManagedRooms").Append(rooms); err != nil {
+			log.Println("Failed to migrate room admins:", err)
+		}
+	}
+}
+
+// 旧版本将结束于午夜的预定记录为 00:00，统一改为 24:00，避免与当天开始时间混淆
+func normalizeEndOfDay() {
+	DB.Model(&models.Booking{}).Where("end_time = ?", "00:00").Update("end_time", "24:00")
+	DB.Model(&models.BookingSeries{}).Where("end_time = ?", "00:00").Update("end_time", "24:00")
 }
 
 // 创建初始数据
@@ -43,4 +92,33 @@ func seedData() {
 		}
 		DB.Create(&rooms)
 	}
+
+	// 创建默认的会议室设施
+	var amenityCount int64
+	DB.Model(&models.Amenity{}).Count(&amenityCount)
+	if amenityCount == 0 {
+		amenities := []models.Amenity{
+			{Code: "projector", Name: "投影仪"},
+			{Code: "video_conference", Name: "视频会议"},
+			{Code: "whiteboard", Name: "白板"},
+			{Code: "phone", Name: "电话"},
+			{Code: "accessible", Name: "无障碍设施"},
+		}
+		DB.Create(&amenities)
+	}
+}
+
+// 为有效和待审批的预定补齐时间段占用记录，已存在的记录会被忽略
+func backfillBookingSlots() {
+	var bookings []models.Booking
+	DB.Where("status IN ?", models.HoldingStatuses).Find(&bookings)
+	for i := range bookings {
+		slots := models.OccupiedSlots(&bookings[i])
+		if len(slots) == 0 {
+			continue
+		}
+		if err := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&slots).Error; err != nil {
+			log.Println("Failed to backfill booking slots:", err)
+		}
+	}
 }
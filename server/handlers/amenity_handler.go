@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+
+	"roomly/database"
+	"roomly/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 获取所有会议室设施
+func GetAmenities(c *gin.Context) {
+	var amenities []models.Amenity
+	if err := database.DB.Order("id asc").Find(&amenities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch amenities"})
+		return
+	}
+	c.JSON(http.StatusOK, amenities)
+}
+
+// 创建会议室设施
+func CreateAmenity(c *gin.Context) {
+	var amenity models.Amenity
+	if err := c.ShouldBindJSON(&amenity); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if amenity.Code == "" || amenity.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code and name are required"})
+		return
+	}
+
+	amenity.ID = 0
+	if err := database.DB.Create(&amenity).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Amenity code already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create amenity"})
+		return
+	}
+	c.JSON(http.StatusCreated, amenity)
+}
+
+// 更新会议室设施
+func UpdateAmenity(c *gin.Context) {
+	var amenity models.Amenity
+	if err := database.DB.First(&amenity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Amenity not found"})
+		return
+	}
+
+	id := amenity.ID
+	if err := c.ShouldBindJSON(&amenity); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	amenity.ID = id
+	if amenity.Code == "" || amenity.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code and name are required"})
+		return
+	}
+
+	if err := database.DB.Save(&amenity).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Amenity code already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update amenity"})
+		return
+	}
+	c.JSON(http.StatusOK, amenity)
+}
+
+// 删除会议室设施，同时从所有会议室中移除
+func DeleteAmenity(c *gin.Context) {
+	var amenity models.Amenity
+	if err := database.DB.First(&amenity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Amenity not found"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("room_amenities").Where("amenity_id = ?", amenity.ID).Delete(nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&amenity).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete amenity"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Amenity deleted successfully"})
+}
+
+// 整体替换会议室的设施，codes 为设施 code 列表
+func UpdateRoomAmenities(c *gin.Context) {
+	room, ok := managedRoom(c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Codes []string `json:"codes"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	amenities := []models.Amenity{}
+	if len(request.Codes) > 0 {
+		if err := database.DB.Where("code IN ?", request.Codes).Find(&amenities).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch amenities"})
+			return
+		}
+	}
+	found := make(map[string]bool)
+	for _, amenity := range amenities {
+		found[amenity.Code] = true
+	}
+	for _, code := range request.Codes {
+		if !found[code] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown amenity: " + code})
+			return
+		}
+	}
+
+	if err := database.DB.Model(room).Association("Amenities").Replace(amenities); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update room amenities"})
+		return
+	}
+
+	preloadRoomDetails(database.DB).First(room, room.ID)
+	c.JSON(http.StatusOK, room)
+}
+
+// 添加会议室照片
+func CreateRoomPhoto(c *gin.Context) {
+	room, ok := managedRoom(c)
+	if !ok {
+		return
+	}
+
+	var photo models.RoomPhoto
+	if err := c.ShouldBindJSON(&photo); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if photo.URL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url is required"})
+		return
+	}
+
+	photo.ID = 0
+	photo.RoomID = room.ID
+	if err := database.DB.Create(&photo).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create room photo"})
+		return
+	}
+	c.JSON(http.StatusCreated, photo)
+}
+
+// 更新会议室照片的地址、说明和顺序
+func UpdateRoomPhoto(c *gin.Context) {
+	photo, ok := managedRoomPhoto(c)
+	if !ok {
+		return
+	}
+
+	id, roomID := photo.ID, photo.RoomID
+	if err := c.ShouldBindJSON(photo); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	photo.ID, photo.RoomID = id, roomID
+	if photo.URL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url is required"})
+		return
+	}
+
+	if err := database.DB.Save(photo).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update room photo"})
+		return
+	}
+	c.JSON(http.StatusOK, photo)
+}
+
+// 删除会议室照片
+func DeleteRoomPhoto(c *gin.Context) {
+	photo, ok := managedRoomPhoto(c)
+	if !ok {
+		return
+	}
+	if err := database.DB.Delete(photo).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete room photo"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Room photo deleted successfully"})
+}
+
+// 会议室位置层级中的楼层
+type locationFloor struct {
+	Floor string   `json:"floor"`
+	Areas []string `json:"areas"`
+}
+
+// 会议室位置层级中的楼栋
+type locationBuilding struct {
+	Building string          `json:"building"`
+	Floors   []locationFloor `json:"floors"`
+}
+
+// 获取所有会议室的位置层级（楼栋 → 楼层 → 区域），用于筛选
+func GetRoomLocations(c *gin.Context) {
+	var locations []struct {
+		Building string
+		Floor    string
+		Area     string
+	}
+	if err := database.DB.Model(&models.Room{}).Distinct("building", "floor", "area").
+		Where("building <> ''").Scan(&locations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch room locations"})
+		return
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		if locations[i].Building != locations[j].Building {
+			return locations[i].Building < locations[j].Building
+		}
+		if locations[i].Floor != locations[j].Floor {
+			return locations[i].Floor < locations[j].Floor
+		}
+		return locations[i].Area < locations[j].Area
+	})
+
+	buildings := []locationBuilding{}
+	for _, location := range locations {
+		if len(buildings) == 0 || buildings[len(buildings)-1].Building != location.Building {
+			buildings = append(buildings, locationBuilding{Building: location.Building, Floors: []locationFloor{}})
+		}
+		building := &buildings[len(buildings)-1]
+		if location.Floor == "" {
+			continue
+		}
+		if len(building.Floors) == 0 || building.Floors[len(building.Floors)-1].Floor != location.Floor {
+			building.Floors = append(building.Floors, locationFloor{Floor: location.Floor, Areas: []string{}})
+		}
+		floor := &building.Floors[len(building.Floors)-1]
+		if location.Area != "" {
+			floor.Areas = append(floor.Areas, location.Area)
+		}
+	}
+	c.JSON(http.StatusOK, buildings)
+}
+
+// 加载路径中的会议室照片并校验当前用户是否可以管理其会议室
+func managedRoomPhoto(c *gin.Context) (*models.RoomPhoto, bool) {
+	room, ok := managedRoom(c)
+	if !ok {
+		return nil, false
+	}
+	var photo models.RoomPhoto
+	if err := database.DB.Where("room_id = ?", room.ID).First(&photo, c.Param("photo_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room photo not found"})
+		return nil, false
+	}
+	return &photo, true
+}
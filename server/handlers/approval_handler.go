@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"roomly/database"
+	"roomly/middleware"
+	"roomly/models"
+	"roomly/notify"
+	"roomly/outbox"
+	"roomly/policy"
+	"roomly/webhook"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 获取待审批的预定，会议室管理员只能查看自己管理的会议室
+func GetPendingBookings(c *gin.Context) {
+	db := database.DB.Where("status = ?", "pending")
+	if roomIDs := policy.ManagedRoomIDs(middleware.CurrentMember(c)); roomIDs != nil {
+		db = db.Where("room_id IN ?", roomIDs)
+	}
+	if roomID := c.Query("room_id"); roomID != "" {
+		db = db.Where("room_id = ?", roomID)
+	}
+
+	var bookings []models.Booking
+	if err := db.Preload("Room").Preload("Member").Preload("BookingUsers").Order("date asc, start_time asc").Find(&bookings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pending bookings"})
+		return
+	}
+	c.JSON(http.StatusOK, bookings)
+}
+
+// 审批通过预定，审批意见可选
+func ApproveBooking(c *gin.Context) {
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	reviewBooking(c, "active", request.Reason)
+}
+
+// 拒绝预定并释放时间段，拒绝理由必填
+func RejectBooking(c *gin.Context) {
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if request.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "拒绝理由不能为空"})
+		return
+	}
+	reviewBooking(c, "rejected", request.Reason)
+}
+
+// 审批待审批的预定，status 为 active（通过）或 rejected（拒绝）
+func reviewBooking(c *gin.Context, status string, reason string) {
+	var booking models.Booking
+	if err := database.DB.Preload("Room").Preload("Member").Preload("BookingUsers").First(&booking, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	// 仅该会议室的管理员和管理员可以审批
+	member := middleware.CurrentMember(c)
+	if err := policy.CanManageRoom(member, booking.RoomID); err != nil {
+		middleware.AbortWithPolicyError(c, err)
+		return
+	}
+	if booking.Status != "pending" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only pending bookings can be reviewed"})
+		return
+	}
+	if status == "active" && !time.Now().Before(bookingClock(booking.Date, booking.StartTime)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The booking has already started"})
+		return
+	}
+
+	// 条件更新，避免与其他审批人或过期任务同时处理；审批结果通知在同一事务中写入
+	now := time.Now()
+	token := middleware.Token(c)
+	var reviewed bool
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Booking{}).
+			Where("id = ? AND status = ?", booking.ID, "pending").
+			Updates(map[string]interface{}{"status": status, "reviewed_by": member.ID, "reviewed_at": now, "review_reason": reason})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		reviewed = true
+		booking.Status = status
+		booking.ReviewedBy = &member.ID
+		booking.ReviewedAt = &now
+		booking.ReviewReason = reason
+		if err := webhook.Publish(tx, webhook.EventUpdated, booking.ID); err != nil {
+			return err
+		}
+
+		if status == "rejected" {
+			if err := releaseSlots(tx, booking.ID); err != nil {
+				return err
+			}
+			return notifyReview(tx, token, &booking, "rejected")
+		}
+		if err := notifyReview(tx, token, &booking, "approved"); err != nil {
+			return err
+		}
+		// 审批通过后才通知参会人员
+		var userIDs []int
+		for _, user := range booking.BookingUsers {
+			userIDs = append(userIDs, int(user.Userid))
+		}
+		calendarURL, err := bookingCalendarURL(tx, &booking)
+		if err != nil {
+			return err
+		}
+		return outbox.Publish(tx, notify.Event{
+			Type:        "remind",
+			UserIDs:     userIDs,
+			Token:       token,
+			Date:        booking.Date,
+			StartTime:   booking.StartTime,
+			EndTime:     booking.EndTime,
+			RoomName:    booking.Room.Name,
+			Reason:      booking.Reason,
+			Attendees:   joinNicknames(booking.BookingUsers),
+			CalendarURL: calendarURL,
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review booking"})
+		return
+	}
+	if !reviewed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking has already been reviewed"})
+		return
+	}
+	go outbox.Deliver()
+	go webhook.Deliver()
+
+	// 释放的时间段提供给候补会员
+	if status == "rejected" {
+		go processWaitlist(token, booking.RoomID, booking.Date)
+	}
+
+	c.JSON(http.StatusOK, booking)
+}
+
+// 定时任务：会议开始时仍未审批的预定标记为过期，释放时间段并通知预定人
+func ExpirePendingBookings() {
+	now := time.Now()
+
+	var bookings []models.Booking
+	database.DB.Preload("Room").Preload("Member").Preload("BookingUsers").
+		Where("status = ? AND date <= ?", "pending", now.Format("2006-01-02")).
+		Find(&bookings)
+
+	for _, booking := range bookings {
+		if now.Before(bookingClock(booking.Date, booking.StartTime)) {
+			continue
+		}
+
+		token := models.SystemToken()
+		var expired bool
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&models.Booking{}).
+				Where("id = ? AND status = ?", booking.ID, "pending").
+				Update("status", "expired")
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			expired = true
+			if err := releaseSlots(tx, booking.ID); err != nil {
+				return err
+			}
+			if err := webhook.Publish(tx, webhook.EventExpired, booking.ID); err != nil {
+				return err
+			}
+			if token == "" {
+				return nil
+			}
+			return notifyReview(tx, token, &booking, "approval_expired")
+		})
+		if err != nil {
+			fmt.Printf("过期待审批预定%d失败: %v\n", booking.ID, err)
+			continue
+		}
+		if !expired {
+			continue
+		}
+
+		if token == "" {
+			fmt.Printf("未配置 DOOTASK_BOT_TOKEN，跳过审批过期通知: 预定%d\n", booking.ID)
+		}
+	}
+}
+
+// 确定新预定的状态：需要审批的会议室中，除该会议室的管理员和管理员外，其他会员的预定需要审批
+func bookingStatus(room *models.Room, member *models.Member) string {
+	if room.RequiresApproval && policy.CanManageRoom(member, room.ID) != nil {
+		return "pending"
+	}
+	return "active"
+}
+
+// 在事务中写入通知，告知会议室管理员有新的预定等待审批
+func notifyApprovalRequest(tx *gorm.DB, token string, booking *models.Booking) error {
+	return outbox.Publish(tx, notify.Event{
+		Type:      "approval_request",
+		AdminIDs:  getRoomAdminIDs(booking.RoomID),
+		Token:     token,
+		Date:      booking.Date,
+		StartTime: booking.StartTime,
+		EndTime:   booking.EndTime,
+		RoomName:  booking.Room.Name,
+		Reason:    booking.Reason,
+		Attendees: joinNicknames(booking.BookingUsers),
+	})
+}
+
+// 在事务中写入通知，告知预定人审批结果，msgType 为 approved、rejected 或 approval_expired；
+// 审批意见作为附带内容，审批过期的原因由消息模板说明
+func notifyReview(tx *gorm.DB, token string, booking *models.Booking, msgType string) error {
+	return outbox.Publish(tx, notify.Event{
+		Type:         msgType,
+		OrganizerIDs: []int{int(booking.Member.DootaskID)},
+		Token:        token,
+		Date:         booking.Date,
+		StartTime:    booking.StartTime,
+		EndTime:      booking.EndTime,
+		RoomName:     booking.Room.Name,
+		Reason:       booking.Reason,
+		Attendees:    joinNicknames(booking.BookingUsers),
+		Content:      []string{booking.ReviewReason},
+	})
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"roomly/database"
+	"roomly/models"
+	"roomly/timeslot"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 忙碌时间一次最多查询的天数
+const maxBusyDays = 31
+
+// 参会人员在同一时间已有的会议
+type attendeeConflict struct {
+	Userid    uint   `json:"userid"`
+	Nickname  string `json:"nickname"`
+	BookingID uint   `json:"booking_id"`
+	RoomID    uint   `json:"room_id"`
+	RoomName  string `json:"room_name"`
+	Date      string `json:"date"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+}
+
+// 用户的一段忙碌时间，不包含预定理由等会议详情
+type busyTime struct {
+	BookingID uint   `json:"booking_id"`
+	RoomID    uint   `json:"room_id"`
+	RoomName  string `json:"room_name"`
+	Date      string `json:"date"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+	Status    string `json:"status"`
+}
+
+// 用户在日期范围内的忙碌时间
+type userBusyTimes struct {
+	Userid uint       `json:"userid"`
+	Busy   []busyTime `json:"busy"`
+}
+
+// 查询用户在所有会议室的忙碌时间：userids 为逗号分隔的 dootask 用户 ID，date 或 start_date/end_date
+func GetBusyTimes(c *gin.Context) {
+	var userids []uint
+	for _, value := range strings.Split(c.Query("userids"), ",") {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid userids"})
+			return
+		}
+		userids = append(userids, uint(id))
+	}
+	if len(userids) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "userids is required"})
+		return
+	}
+
+	startDate := c.Query("start_date")
+	endDate := c.Query("end_date")
+	if date := c.Query("date"); date != "" {
+		startDate, endDate = date, date
+	}
+	if startDate == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date or start_date is required"})
+		return
+	}
+	if endDate == "" {
+		endDate = startDate
+	}
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+		return
+	}
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+		return
+	}
+	if end.Before(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must not be before start_date"})
+		return
+	}
+	if end.Sub(start) >= maxBusyDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Range must not exceed 31 days"})
+		return
+	}
+
+	bookings, err := userBookings(database.DB, userids, startDate, endDate, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+		return
+	}
+
+	results := []userBusyTimes{}
+	for _, userid := range userids {
+		result := userBusyTimes{Userid: userid, Busy: []busyTime{}}
+		for _, booking := range bookings {
+			if !involvesUser(&booking, userid) {
+				continue
+			}
+			result.Busy = append(result.Busy, busyTime{
+				BookingID: booking.ID,
+				RoomID:    booking.RoomID,
+				RoomName:  booking.Room.Name,
+				Date:      booking.Date,
+				StartTime: booking.StartTime,
+				EndTime:   booking.EndTime,
+				Status:    booking.Status,
+			})
+		}
+		results = append(results, result)
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// 查找参会人员和预定人在同一时间已有的会议，预定人按其 dootask_id 匹配；excludeID 为修改中的预定
+func findAttendeeConflicts(db *gorm.DB, users []models.BookingUser, organizer *models.Member, date string, window timeslot.Window, excludeID uint) ([]attendeeConflict, error) {
+	nicknames := make(map[uint]string)
+	var userids []uint
+	add := func(userid uint, nickname string) {
+		if _, ok := nicknames[userid]; ok {
+			return
+		}
+		nicknames[userid] = nickname
+		userids = append(userids, userid)
+	}
+	add(organizer.DootaskID, organizer.Name)
+	for _, user := range users {
+		add(user.Userid, user.Nickname)
+	}
+
+	bookings, err := userBookings(db, userids, date, date, excludeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []attendeeConflict
+	for _, booking := range bookings {
+		if !window.Overlaps(timeslot.MustWindow(booking.StartTime, booking.EndTime)) {
+			continue
+		}
+		for _, userid := range userids {
+			if !involvesUser(&booking, userid) {
+				continue
+			}
+			conflicts = append(conflicts, attendeeConflict{
+				Userid:    userid,
+				Nickname:  nicknames[userid],
+				BookingID: booking.ID,
+				RoomID:    booking.RoomID,
+				RoomName:  booking.Room.Name,
+				Date:      booking.Date,
+				StartTime: booking.StartTime,
+				EndTime:   booking.EndTime,
+			})
+		}
+	}
+	return conflicts, nil
+}
+
+// 判断冲突中是否包含预定人自己
+func hasOrganizerConflict(conflicts []attendeeConflict, organizer *models.Member) bool {
+	for _, conflict := range conflicts {
+		if conflict.Userid == organizer.DootaskID {
+			return true
+		}
+	}
+	return false
+}
+
+// 加载用户作为参会人员或预定人的占用时间段的预定
+func userBookings(db *gorm.DB, userids []uint, startDate, endDate string, excludeID uint) ([]models.Booking, error) {
+	var bookings []models.Booking
+	err := db.Preload("Room").Preload("Member").Preload("BookingUsers").
+		Where("date BETWEEN ? AND ? AND status IN ? AND id <> ?", startDate, endDate, models.HoldingStatuses, excludeID).
+		Where("id IN (?) OR member_id IN (?)",
+			db.Model(&models.BookingUser{}).Select("booking_id").Where("userid IN ?", userids),
+			db.Model(&models.Member{}).Select("id").Where("dootask_id IN ?", userids)).
+		Order("date asc, start_time asc").Find(&bookings).Error
+	return bookings, err
+}
+
+// 判断用户是否为预定的参会人员或预定人
+func involvesUser(booking *models.Booking, userid uint) bool {
+	if booking.Member.DootaskID == userid {
+		return true
+	}
+	for _, user := range booking.BookingUsers {
+		if user.Userid == userid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"roomly/database"
+	"roomly/middleware"
+	"roomly/models"
+	"roomly/notify"
+	"roomly/outbox"
+	"roomly/policy"
+	"roomly/timeslot"
+	"roomly/webhook"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 获取会议室的关闭时间段，默认只返回尚未结束的
+func GetRoomBlackouts(c *gin.Context) {
+	id := c.Param("id")
+	from := c.DefaultQuery("from", time.Now().Format("2006-01-02"))
+
+	var blackouts []models.RoomBlackout
+	if err := database.DB.Where("room_id = ? AND end_date >= ?", id, from).Preload("Member").Order("start_date asc, start_time asc").Find(&blackouts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch room blackouts"})
+		return
+	}
+	c.JSON(http.StatusOK, blackouts)
+}
+
+// 创建会议室关闭时间段，返回与之冲突的预定；cancel_conflicts 为 true 时自动取消冲突的预定并通知参会人员
+func CreateRoomBlackout(c *gin.Context) {
+	room, ok := managedRoom(c)
+	if !ok {
+		return
+	}
+
+	var request models.RoomBlackoutRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.EndDate == "" {
+		request.EndDate = request.StartDate
+	}
+	blackout := models.RoomBlackout{
+		RoomID:    room.ID,
+		MemberID:  middleware.CurrentMember(c).ID,
+		StartDate: request.StartDate,
+		StartTime: request.StartTime,
+		EndDate:   request.EndDate,
+		EndTime:   request.EndTime,
+		Reason:    request.Reason,
+	}
+	if err := validateBlackout(&blackout); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var conflicts []models.Booking
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&blackout).Error; err != nil {
+			return err
+		}
+		blackout.Room = *room
+		var err error
+		if conflicts, err = blackoutConflicts(tx, &blackout); err != nil {
+			return err
+		}
+		if request.CancelConflicts {
+			return cancelForBlackout(tx, middleware.Token(c), &blackout, conflicts)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create room blackout"})
+		return
+	}
+
+	cancelled := 0
+	if request.CancelConflicts {
+		go outbox.Deliver()
+		go webhook.Deliver()
+		cancelled = len(conflicts)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"blackout":  blackout,
+		"conflicts": conflicts,
+		"cancelled": cancelled,
+	})
+}
+
+// 获取与关闭时间段冲突的有效预定
+func GetRoomBlackoutConflicts(c *gin.Context) {
+	blackout, ok := managedBlackout(c)
+	if !ok {
+		return
+	}
+
+	conflicts, err := blackoutConflicts(database.DB, blackout)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch conflicting bookings"})
+		return
+	}
+	c.JSON(http.StatusOK, conflicts)
+}
+
+// 取消与关闭时间段冲突的所有有效预定，并通知参会人员关闭原因
+func CancelRoomBlackoutConflicts(c *gin.Context) {
+	blackout, ok := managedBlackout(c)
+	if !ok {
+		return
+	}
+
+	var conflicts []models.Booking
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if conflicts, err = blackoutConflicts(tx, blackout); err != nil {
+			return err
+		}
+		return cancelForBlackout(tx, middleware.Token(c), blackout, conflicts)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel conflicting bookings"})
+		return
+	}
+
+	go outbox.Deliver()
+	go webhook.Deliver()
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Conflicting bookings cancelled successfully",
+		"cancelled": len(conflicts),
+	})
+}
+
+// 删除会议室关闭时间段，已取消的预定不会恢复
+func DeleteRoomBlackout(c *gin.Context) {
+	blackout, ok := managedBlackout(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Delete(blackout).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete room blackout"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Room blackout deleted successfully"})
+}
+
+// 加载路径中的会议室并校验当前用户是否可以管理
+func managedRoom(c *gin.Context) (*models.Room, bool) {
+	var room models.Room
+	if err := database.DB.First(&room, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		return nil, false
+	}
+	if err := policy.CanManageRoom(middleware.CurrentMember(c), room.ID); err != nil {
+		middleware.AbortWithPolicyError(c, err)
+		return nil, false
+	}
+	return &room, true
+}
+
+// 加载路径中的关闭时间段并校验当前用户是否可以管理其会议室
+func managedBlackout(c *gin.Context) (*models.RoomBlackout, bool) {
+	room, ok := managedRoom(c)
+	if !ok {
+		return nil, false
+	}
+	var blackout models.RoomBlackout
+	if err := database.DB.Where("room_id = ?", room.ID).First(&blackout, c.Param("blackout_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room blackout not found"})
+		return nil, false
+	}
+	blackout.Room = *room
+	return &blackout, true
+}
+
+// 校验关闭时间段的日期和时间格式，结束时间必须晚于开始时间
+func validateBlackout(blackout *models.RoomBlackout) error {
+	if _, err := time.Parse("2006-01-02", blackout.StartDate); err != nil {
+		return errors.New("Invalid start_date format, use YYYY-MM-DD")
+	}
+	if _, err := time.Parse("2006-01-02", blackout.EndDate); err != nil {
+		return errors.New("Invalid end_date format, use YYYY-MM-DD")
+	}
+	start, err := timeslot.Parse(blackout.StartTime)
+	if err != nil {
+		return errors.New("Invalid start_time format, use HH:MM")
+	}
+	end, err := timeslot.Parse(blackout.EndTime)
+	if err != nil {
+		return errors.New("Invalid end_time format, use HH:MM")
+	}
+	blackout.StartTime = timeslot.Format(start)
+	blackout.EndTime = timeslot.Format(end)
+	if blackout.EndDate < blackout.StartDate || (blackout.EndDate == blackout.StartDate && end <= start) {
+		return errors.New("Blackout end must be after its start")
+	}
+	return nil
+}
+
+// 查找与指定日期时间区间重叠的关闭时间段，没有时返回 nil
+func findBlackout(db *gorm.DB, roomID uint, date string, window timeslot.Window) *models.RoomBlackout {
+	var blackouts []models.RoomBlackout
+	db.Where("room_id = ? AND start_date <= ? AND end_date >= ?", roomID, date, date).Find(&blackouts)
+	for i := range blackouts {
+		if closed, ok := blackouts[i].WindowOn(date); ok && closed.Overlaps(window) {
+			return &blackouts[i]
+		}
+	}
+	return nil
+}
+
+// 关闭时间段冲突的错误信息
+func blackoutError(blackout *models.RoomBlackout) error {
+	return fmt.Errorf("Room is closed from %s: %s", blackout.Describe(), blackout.Reason)
+}
+
+// 标记处于关闭时间段内的时间段
+func markBlockedSlots(slots []models.TimeSlot, blackouts []models.RoomBlackout, date string) []models.TimeSlot {
+	for i := range slots {
+		window := timeslot.MustWindow(slots[i].Start, slots[i].End)
+		for j := range blackouts {
+			if closed, ok := blackouts[j].WindowOn(date); ok && closed.Overlaps(window) {
+				slots[i].IsBooked = true
+				slots[i].IsBlocked = true
+				break
+			}
+		}
+	}
+	return slots
+}
+
+// 查找与关闭时间段冲突的有效预定
+func blackoutConflicts(db *gorm.DB, blackout *models.RoomBlackout) ([]models.Booking, error) {
+	var bookings []models.Booking
+	if err := db.Where("room_id = ? AND status IN ? AND date BETWEEN ? AND ?", blackout.RoomID, models.HoldingStatuses, blackout.StartDate, blackout.EndDate).
+		Preload("Member").Preload("BookingUsers").Order("date asc, start_time asc").Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+
+	conflicts := []models.Booking{}
+	for _, booking := range bookings {
+		closed, ok := blackout.WindowOn(booking.Date)
+		if ok && closed.Overlaps(timeslot.MustWindow(booking.StartTime, booking.EndTime)) {
+			conflicts = append(conflicts, booking)
+		}
+	}
+	return conflicts, nil
+}
+
+// 取消与关闭时间段冲突的预定、释放时间段并发布预定取消事件
+func cancelForBlackout(tx *gorm.DB, token string, blackout *models.RoomBlackout, bookings []models.Booking) error {
+	if len(bookings) == 0 {
+		return nil
+	}
+	var ids []uint
+	for i := range bookings {
+		ids = append(ids, bookings[i].ID)
+		bookings[i].Status = "cancelled"
+		bookings[i].CancelReason = "会议室关闭：" + blackout.Reason
+	}
+	if err := tx.Model(&models.Booking{}).Where("id IN ?", ids).
+		Updates(map[string]interface{}{"status": "cancelled", "cancel_reason": "会议室关闭：" + blackout.Reason}).Error; err != nil {
+		return err
+	}
+	if err := releaseSlots(tx, ids...); err != nil {
+		return err
+	}
+	if err := webhook.Publish(tx, webhook.EventCancelled, ids...); err != nil {
+		return err
+	}
+	return notifyBlackoutCancellation(tx, token, blackout, bookings)
+}
+
+// 在事务中写入通知，告知被取消预定的预定人和参会人员会议室关闭的时间和原因
+func notifyBlackoutCancellation(tx *gorm.DB, token string, blackout *models.RoomBlackout, bookings []models.Booking) error {
+	for _, booking := range bookings {
+		userIDs := []int{int(booking.Member.DootaskID)}
+		var attendeeNames []string
+		for _, user := range booking.BookingUsers {
+			userIDs = append(userIDs, int(user.Userid))
+			attendeeNames = append(attendeeNames, user.Nickname)
+		}
+		if err := outbox.Publish(tx, notify.Event{
+			Type:      "closure",
+			UserIDs:   userIDs,
+			Token:     token,
+			Date:      booking.Date,
+			StartTime: booking.StartTime,
+			EndTime:   booking.EndTime,
+			RoomName:  blackout.Room.Name,
+			Reason:    booking.Reason,
+			Attendees: strings.Join(attendeeNames, "、"),
+			Content:   []string{blackout.StartDate, blackout.StartTime, blackout.EndDate, blackout.EndTime, blackout.Reason},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,18 +1,37 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"roomly/database"
+	"roomly/middleware"
 	"roomly/models"
+	"roomly/notify"
+	"roomly/outbox"
+	"roomly/policy"
+	"roomly/timeslot"
+	"roomly/webhook"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// 最多可提前预定的天数
+const maxAdvanceDays = 30
+
+// 预定响应，附带不影响预定结果的提示信息
+type bookingResponse struct {
+	models.Booking
+	Warnings          []string           `json:"warnings,omitempty"`
+	SuggestedRooms    []models.Room      `json:"suggested_rooms,omitempty"`    // 超员时推荐的同一时间空闲的更大会议室
+	AttendeeConflicts []attendeeConflict `json:"attendee_conflicts,omitempty"` // 同一时间已有其他会议的参会人员
+}
+
 // 获取所有预定记录
 func GetBookings(c *gin.Context) {
 	// 解析分页参数
@@ -49,6 +68,10 @@ func GetBookings(c *gin.Context) {
 
 	var total int64
 	db := database.DB.Model(&models.Booking{})
+	// 会议室管理员只能查看自己管理的会议室
+	if roomIDs := policy.ManagedRoomIDs(middleware.CurrentMember(c)); roomIDs != nil {
+		db = db.Where("room_id IN ?", roomIDs)
+	}
 	if startDate != "" {
 		db = db.Where("date >= ?", startDate)
 	}
@@ -62,6 +85,12 @@ func GetBookings(c *gin.Context) {
 		db = db.Where("status = ?", "expired")
 	} else if status == "cancelled" {
 		db = db.Where("status = ?", "cancelled")
+	} else if status == "no_show" {
+		db = db.Where("status = ?", "no_show")
+	} else if status == "pending" {
+		db = db.Where("status = ?", "pending")
+	} else if status == "rejected" {
+		db = db.Where("status = ?", "rejected")
 	}
 	db.Count(&total)
 
@@ -81,6 +110,17 @@ func GetBookings(c *gin.Context) {
 func GetMemberBookings(c *gin.Context) {
 	memberID := c.Param("id")
 
+	// 仅会员本人和管理员可以查看
+	parsedID, err := strconv.ParseUint(memberID, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid member id"})
+		return
+	}
+	if err := policy.CanViewMember(middleware.CurrentMember(c), uint(parsedID)); err != nil {
+		middleware.AbortWithPolicyError(c, err)
+		return
+	}
+
 	// 解析分页参数
 	pageStr := c.DefaultQuery("page", "1")
 	pageSizeStr := c.DefaultQuery("page_size", "20")
@@ -110,6 +150,12 @@ func GetMemberBookings(c *gin.Context) {
 		db = db.Where("(date < ? OR (date = ? AND end_time <= ?))", time.Now().Format("2006-01-02"), time.Now().Format("2006-01-02"), time.Now().Format("15:04"))
 	} else if status == "cancelled" {
 		db = db.Where("status = ?", "cancelled")
+	} else if status == "no_show" {
+		db = db.Where("status = ?", "no_show")
+	} else if status == "pending" {
+		db = db.Where("status = ?", "pending")
+	} else if status == "rejected" {
+		db = db.Where("status = ?", "rejected")
 	}
 	db.Count(&total)
 
@@ -125,7 +171,7 @@ func GetMemberBookings(c *gin.Context) {
 	})
 }
 
-// 获取指定会议室的预定记录
+// 获取指定会议室的预定记录，私密会议的详情按查看权限隐藏
 func GetRoomBookings(c *gin.Context) {
 	roomID := c.Param("id")
 	var bookings []models.Booking
@@ -133,9 +179,26 @@ func GetRoomBookings(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch room bookings"})
 		return
 	}
+	member := middleware.CurrentMember(c)
+	for i := range bookings {
+		redactBooking(member, &bookings[i])
+	}
 	c.JSON(http.StatusOK, bookings)
 }
 
+// 无权查看私密会议详情时隐藏预定理由、取消理由、审批意见和参会人员（需要已加载 BookingUsers），
+// 返回是否已隐藏
+func redactBooking(member *models.Member, booking *models.Booking) bool {
+	if policy.CanViewBookingDetails(member, booking) == nil {
+		return false
+	}
+	booking.Reason = ""
+	booking.CancelReason = ""
+	booking.ReviewReason = ""
+	booking.BookingUsers = []models.BookingUser{}
+	return true
+}
+
 // 获取指定日期和会议室的可用时间段
 func GetAvailableSlots(c *gin.Context) {
 	roomID := c.Query("room_id")
@@ -147,28 +210,61 @@ func GetAvailableSlots(c *gin.Context) {
 	}
 
 	// 验证日期格式
-	if _, err := time.Parse("2006-01-02", date); err != nil {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
 		return
 	}
 
+	parsedRoomID, err := strconv.ParseUint(roomID, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room_id"})
+		return
+	}
+	room, err := loadRoom(uint(parsedRoomID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		return
+	}
+
 	// 获取该日期该会议室的所有预定
 	var bookings []models.Booking
-	if err := database.DB.Where("room_id = ? AND date = ? AND status = ?", roomID, date, "active").Find(&bookings).Error; err != nil {
+	if err := database.DB.Where("room_id = ? AND date = ? AND status IN ?", roomID, date, models.HoldingStatuses).Find(&bookings).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
 		return
 	}
 
-	// 生成所有可能的时间段（24小时，每30分钟一个时间段）
-	allSlots := generateAllTimeSlots()
+	// 按会议室的时间段长度和营业时间生成所有可能的时间段
+	allSlots := generateTimeSlots(room, day)
 
-	// 标记已被预定的时间段
+	// 标记已被预定的时间段，待审批的预定单独标记
 	slotsWithBookingStatus := markBookedSlots(allSlots, bookings)
 
-	c.JSON(http.StatusOK, models.AvailableSlots{
+	// 标记会议室关闭的时间段
+	var blackouts []models.RoomBlackout
+	database.DB.Where("room_id = ? AND start_date <= ? AND end_date >= ?", room.ID, date, date).Find(&blackouts)
+	slotsWithBookingStatus = markBlockedSlots(slotsWithBookingStatus, blackouts, date)
+
+	// 标记正在等待其他候补会员认领的时间段
+	offers := waitlistHolds(database.DB, room.ID, date, middleware.CurrentMember(c).ID)
+	slotsWithBookingStatus = markHeldSlots(slotsWithBookingStatus, offers)
+
+	// 非工作日按会议室策略提示或全部标记为不可预定
+	response := models.AvailableSlots{
 		Date:      date,
 		TimeSlots: slotsWithBookingStatus,
-	})
+	}
+	if room.HolidayPolicy != models.HolidayAllow {
+		response.IsHoliday, response.HolidayName = nonWorkingDay(database.DB, date)
+		if response.IsHoliday && room.HolidayPolicy == models.HolidayReject {
+			for i := range response.TimeSlots {
+				response.TimeSlots[i].IsBooked = true
+				response.TimeSlots[i].IsBlocked = true
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // 创建预定
@@ -180,111 +276,196 @@ func CreateBooking(c *gin.Context) {
 	}
 
 	// 验证日期格式
-	if _, err := time.Parse("2006-01-02", request.Date); err != nil {
+	requestDate, err := time.Parse("2006-01-02", request.Date)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
 		return
 	}
 
 	// 验证预定不能超过30天
-	requestDate, _ := time.Parse("2006-01-02", request.Date)
-	if requestDate.After(time.Now().AddDate(0, 0, 30)) {
+	if requestDate.After(time.Now().AddDate(0, 0, maxAdvanceDays)) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot book more than 30 days in advance"})
 		return
 	}
 
-	// 验证时间段连续性
-	if !areTimeSlotsConsecutive(request.TimeSlots) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Time slots must be consecutive"})
+	room, err := loadRoom(request.RoomID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		return
+	}
+	if !room.IsOpen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Room is closed"})
 		return
 	}
 
-	// 检查时间段是否可用
-	if !areSlotsAvailable(request.RoomID, request.Date, request.TimeSlots) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Some time slots are already booked"})
+	// 验证时间段连续性、营业时间和时长限制
+	window, err := resolveBookingWindow(room, request.Date, request.TimeSlots)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 创建预定记录
-	startTime := request.TimeSlots[0]
-	endTime := getEndTime(request.TimeSlots[len(request.TimeSlots)-1])
+	// 检查非工作日策略
+	var warnings []string
+	warning, err := checkHoliday(database.DB, room, request.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if warning != "" {
+		warnings = append(warnings, warning)
+	}
 
-	booking := models.Booking{
-		RoomID:    request.RoomID,
-		MemberID:  request.MemberID,
-		Date:      request.Date,
-		StartTime: startTime,
-		EndTime:   endTime,
-		Reason:    request.Reason,
-		Status:    "active",
+	// 检查会议室是否处于关闭时间段
+	if blackout := findBlackout(database.DB, room.ID, request.Date, window); blackout != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": blackoutError(blackout).Error(), "blackout": blackout})
+		return
 	}
 
-	if err := database.DB.Create(&booking).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
+	member := middleware.CurrentMember(c)
+
+	// 检查参会人数是否超过会议室容量，超员时推荐同一时间空闲的更大会议室
+	var suggestions []models.Room
+	warning, err = checkCapacity(room, member, request.BookingUsers, request.CapacityOverride)
+	if err != nil || warning != "" {
+		suggestions = largerFreeRooms(room, request.Date, window, attendeeCount(request.BookingUsers), member.ID)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, capacityError(room, request.BookingUsers, err, suggestions))
 		return
 	}
+	if warning != "" {
+		warnings = append(warnings, warning)
+	}
 
-	for _, user := range request.BookingUsers {
-		bookingUser := models.BookingUser{
-			BookingID: booking.ID,
-			Userid:    user.Userid,
-			Nickname:  user.Nickname,
-		}
-		if err := database.DB.Create(&bookingUser).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking user"})
-			return
-		}
+	// 检查参会人员同一时间是否已有其他会议，预定人自己冲突时可以选择拒绝预定
+	conflicts, err := findAttendeeConflicts(database.DB, request.BookingUsers, member, request.Date, window, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check attendee conflicts"})
+		return
+	}
+	if request.BlockOwnConflict && hasOrganizerConflict(conflicts, member) {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already have an overlapping booking", "attendee_conflicts": conflicts})
+		return
+	}
+	if len(conflicts) > 0 {
+		warnings = append(warnings, "Some attendees already have overlapping bookings")
 	}
 
-	// 返回包含关联数据的预定记录
-	database.DB.Preload("Room").Preload("Member").Preload("BookingUsers").First(&booking, booking.ID)
+	// 检查时间段是否正在等待候补会员认领
+	if hold := findWaitlistHold(database.DB, room.ID, request.Date, window, member.ID); hold != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": waitlistHoldError(hold).Error(), "can_join_waitlist": true})
+		return
+	}
+
+	// 检查时间段是否可用，已被预定时可以加入候补
+	if !areSlotsAvailable(request.RoomID, request.Date, window) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Some time slots are already booked", "can_join_waitlist": true})
+		return
+	}
+
+	// 创建预定记录，需要审批的会议室先占用时间段等待审批
+	booking := models.Booking{
+		RoomID:    request.RoomID,
+		MemberID:  member.ID,
+		Date:      request.Date,
+		StartTime: window.StartClock(),
+		EndTime:   window.EndClock(),
+		Reason:    request.Reason,
+		IsPrivate: request.IsPrivate,
+		Status:    bookingStatus(room, member),
+	}
 
 	// 获取所有参会用户ID
 	var userIDs []int
 	for _, user := range request.BookingUsers {
 		userIDs = append(userIDs, int(user.Userid))
 	}
-	// 查找所有会议室管理员 dootask_id
-	var adminIDs []int
-	var admins []models.Member
-	database.DB.Where("is_room_admin = ?", true).Find(&admins)
-	for _, admin := range admins {
-		adminIDs = append(adminIDs, int(admin.DootaskID))
-	}
-	// 从header获取token
-	authHeader := c.GetHeader("Authorization")
-	var token string
-	if len(authHeader) > 0 {
-		if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-			token = authHeader[7:]
-		} else {
-			token = authHeader
-		}
-	}
-	// 获取会议室名称
-	var room models.Room
-	roomName := ""
-	if err := database.DB.First(&room, request.RoomID).Error; err == nil {
-		roomName = room.Name
-	}
+	// 查找该会议室的管理员 dootask_id
+	adminIDs := getRoomAdminIDs(request.RoomID)
+	// 获取当前请求的token
+	token := middleware.Token(c)
 	// 获取所有参会用户昵称
 	var attendeeNames []string
 	for _, user := range request.BookingUsers {
 		attendeeNames = append(attendeeNames, user.Nickname)
 	}
 	attendees := strings.Join(attendeeNames, "、")
+
+	// 配额检查、预定、参会人员、时间段占用和会议通知在同一事务中完成，并发请求只有一个能通过
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := enforceQuota(tx, room, member, request.Date, window, 0); err != nil {
+			return err
+		}
+		if err := insertBooking(tx, &booking, request.BookingUsers); err != nil {
+			return err
+		}
+		// 待审批的预定只通知会议室管理员，审批通过后再通知参会人员
+		if booking.Status == "pending" {
+			return outbox.Publish(tx, notify.Event{
+				Type:      "approval_request",
+				AdminIDs:  adminIDs,
+				Token:     token,
+				Date:      request.Date,
+				StartTime: booking.StartTime,
+				EndTime:   booking.EndTime,
+				RoomName:  room.Name,
+				Reason:    request.Reason,
+				Attendees: attendees,
+			})
+		}
+		calendarURL, err := bookingCalendarURL(tx, &booking)
+		if err != nil {
+			return err
+		}
+		return outbox.Publish(tx, notify.Event{
+			Type:        "remind",
+			UserIDs:     userIDs,
+			AdminIDs:    adminIDs,
+			Token:       token,
+			Date:        request.Date,
+			StartTime:   booking.StartTime,
+			EndTime:     booking.EndTime,
+			RoomName:    room.Name,
+			Reason:      request.Reason,
+			Attendees:   attendees,
+			CalendarURL: calendarURL,
+		})
+	})
+	var exceeded *quotaExceededError
+	if errors.As(err, &exceeded) {
+		c.JSON(http.StatusBadRequest, quotaError(exceeded.policy, exceeded.violation))
+		return
+	}
+	if errors.Is(err, errSlotsTaken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Some time slots are already booked"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
+		return
+	}
 	// 异步发送会议通知
-	go models.SendMessageWithToken(userIDs, adminIDs, token, request.Date, request.TimeSlots, roomName, "remind", request.Reason, attendees, "")
+	go outbox.Deliver()
+	go webhook.Deliver()
+
+	// 返回包含关联数据的预定记录
+	database.DB.Preload("Room").Preload("Member").Preload("BookingUsers").First(&booking, booking.ID)
+	if booking.Status == "pending" {
+		warnings = append(warnings, "Booking is pending approval by the room admins")
+	}
 
-	c.JSON(http.StatusCreated, booking)
+	c.JSON(http.StatusCreated, bookingResponse{Booking: booking, Warnings: warnings, SuggestedRooms: suggestions, AttendeeConflicts: conflicts})
 }
 
 // 取消预定
 func CancelBooking(c *gin.Context) {
 	id := c.Param("id")
 
-	// 解析请求体，获取取消理由
+	// 解析请求体，获取取消理由和范围（周期预定支持 this、following、all）
 	var request struct {
 		CancelReason string `json:"cancel_reason"`
+		Scope        string `json:"scope"`
 	}
 	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
@@ -303,97 +484,483 @@ func CancelBooking(c *gin.Context) {
 		return
 	}
 
+	// 仅预定人、会议室管理员和管理员可以取消
+	if err := policy.CanManageBooking(middleware.CurrentMember(c), &booking); err != nil {
+		middleware.AbortWithPolicyError(c, err)
+		return
+	}
+
+	// 周期预定按范围批量取消
+	if booking.SeriesID != nil && (request.Scope == "following" || request.Scope == "all") {
+		cancelBookingSeries(c, booking, request.Scope, request.CancelReason)
+		return
+	}
+	if request.Scope != "" && request.Scope != "this" && request.Scope != "following" && request.Scope != "all" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be one of this, following, all"})
+		return
+	}
+
 	// 幂等性校验
 	if booking.Status == "cancelled" {
 		c.JSON(http.StatusOK, gin.H{"message": "Booking already cancelled"})
 		return
 	}
 
+	// 获取所有参会人员 userID
+	var userIDs []int
+	for _, user := range booking.BookingUsers {
+		userIDs = append(userIDs, int(user.Userid))
+	}
+	// 查找该会议室的管理员 dootask_id
+	adminIDs := getRoomAdminIDs(booking.RoomID)
+	// 获取 token
+	token := middleware.Token(c)
+	// 获取所有参会用户昵称
+	var attendeeNames []string
+	for _, user := range booking.BookingUsers {
+		attendeeNames = append(attendeeNames, user.Nickname)
+	}
+	attendees := strings.Join(attendeeNames, "、")
+
 	booking.Status = "cancelled"
 	booking.CancelReason = request.CancelReason
-	if err := database.DB.Save(&booking).Error; err != nil {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(&booking).Error; err != nil {
+			return err
+		}
+		if err := releaseSlots(tx, booking.ID); err != nil {
+			return err
+		}
+		if err := webhook.Publish(tx, webhook.EventCancelled, booking.ID); err != nil {
+			return err
+		}
+		// 取消通知与取消操作在同一事务中写入（消息内容由 sendmessge.go 内部组装）
+		if len(userIDs) == 0 {
+			return nil
+		}
+		return outbox.Publish(tx, notify.Event{
+			Type:      "cancel",
+			UserIDs:   userIDs,
+			AdminIDs:  adminIDs,
+			Token:     token,
+			Date:      booking.Date,
+			StartTime: booking.StartTime,
+			EndTime:   booking.EndTime,
+			RoomName:  booking.Room.Name,
+			Reason:    booking.Reason,
+			Attendees: attendees,
+			Content:   []string{request.CancelReason},
+		})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel booking"})
 		return
 	}
+	go outbox.Deliver()
+	go webhook.Deliver()
 
-	// 获取所有参会人员 userID
-	var userIDs []int
-	for _, user := range booking.BookingUsers {
-		userIDs = append(userIDs, int(user.Userid))
+	// 释放的时间段提供给候补会员
+	go processWaitlist(token, booking.RoomID, booking.Date)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Booking cancelled successfully"})
+}
+
+// 修改预定（改期、更换会议室、修改理由和参会人员），只发送一条变更通知
+func UpdateBooking(c *gin.Context) {
+	id := c.Param("id")
+
+	var request models.BookingUpdateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	// 查找所有会议室管理员 dootask_id
-	var adminIDs []int
-	var admins []models.Member
-	database.DB.Where("is_room_admin = ?", true).Find(&admins)
-	for _, admin := range admins {
-		adminIDs = append(adminIDs, int(admin.DootaskID))
-	}
-	fmt.Printf("adminIDs: %v\n", adminIDs)
-	// 获取会议室名称
-	roomName := ""
-	if booking.Room.Name != "" {
-		roomName = booking.Room.Name
-	}
-	// 组装时间段
-	timeSlots := []string{booking.StartTime, booking.EndTime}
-	// 获取 token
-	authHeader := c.GetHeader("Authorization")
-	var token string
-	if len(authHeader) > 0 {
-		if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-			token = authHeader[7:]
-		} else {
-			token = authHeader
+
+	var booking models.Booking
+	if err := database.DB.Preload("BookingUsers").Preload("Room").First(&booking, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	// 仅预定人、会议室管理员和管理员可以修改
+	if err := policy.CanManageBooking(middleware.CurrentMember(c), &booking); err != nil {
+		middleware.AbortWithPolicyError(c, err)
+		return
+	}
+	if booking.Status != "active" && booking.Status != "pending" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only active or pending bookings can be modified"})
+		return
+	}
+
+	updated := booking
+	if request.RoomID != 0 {
+		updated.RoomID = request.RoomID
+	}
+	if request.Date != "" {
+		// 验证日期格式
+		requestDate, err := time.Parse("2006-01-02", request.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+			return
 		}
+		// 验证预定不能超过30天
+		if requestDate.After(time.Now().AddDate(0, 0, maxAdvanceDays)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot book more than 30 days in advance"})
+			return
+		}
+		updated.Date = request.Date
+	}
+	if request.Reason != "" {
+		updated.Reason = request.Reason
+	}
+	if request.IsPrivate != nil {
+		updated.IsPrivate = *request.IsPrivate
+	}
+
+	// 更换会议室时检查会议室是否存在且开放
+	room, err := loadRoom(updated.RoomID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		return
 	}
-	// 发送取消通知（消息内容由 sendmessge.go 内部组装）
-	if len(userIDs) > 0 {
-		// 获取所有参会用户昵称
-		var attendeeNames []string
-		for _, user := range booking.BookingUsers {
-			attendeeNames = append(attendeeNames, user.Nickname)
+	if updated.RoomID != booking.RoomID {
+		if !room.IsOpen {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Room is closed"})
+			return
 		}
-		attendees := strings.Join(attendeeNames, "、")
+		updated.Room = *room
+	}
 
-		go models.SendMessageWithToken(userIDs, adminIDs, token, booking.Date, timeSlots, roomName, "cancel", booking.Reason, attendees, request.CancelReason)
+	window := timeslot.MustWindow(booking.StartTime, booking.EndTime)
+	if len(request.TimeSlots) > 0 {
+		// 验证时间段连续性
+		window, err = timeslot.Span(request.TimeSlots, room.SlotLength())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errSlotsNotConsecutive.Error()})
+			return
+		}
+		updated.StartTime = window.StartClock()
+		updated.EndTime = window.EndClock()
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Booking cancelled successfully"})
+	// 调整了会议室、日期或时间时按会议室规则重新校验，并按会议室的审批设置重新确定状态，
+	// 已审批通过的预定改期后需要重新审批
+	rescheduled := updated.RoomID != booking.RoomID || updated.Date != booking.Date || len(request.TimeSlots) > 0
+	if rescheduled {
+		updated.Status = bookingStatus(room, middleware.CurrentMember(c))
+		if updated.Status == "pending" && booking.Status != "pending" {
+			updated.ReviewedBy = nil
+			updated.ReviewedAt = nil
+			updated.ReviewReason = ""
+		}
+		day, _ := time.Parse("2006-01-02", updated.Date)
+		if err := checkBookingWindow(room, day, window); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if blackout := findBlackout(database.DB, room.ID, updated.Date, window); blackout != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": blackoutError(blackout).Error(), "blackout": blackout})
+			return
+		}
+	}
+
+	// 更换会议室或日期时检查非工作日策略
+	var warnings []string
+	if updated.RoomID != booking.RoomID || updated.Date != booking.Date {
+		warning, err := checkHoliday(database.DB, room, updated.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	// 更换会议室或参会人员时按新会议室的容量检查参会人数
+	var suggestions []models.Room
+	if updated.RoomID != booking.RoomID || request.BookingUsers != nil {
+		users := booking.BookingUsers
+		if request.BookingUsers != nil {
+			users = request.BookingUsers
+		}
+		warning, err := checkCapacity(room, middleware.CurrentMember(c), users, request.CapacityOverride)
+		if err != nil || warning != "" {
+			suggestions = largerFreeRooms(room, updated.Date, window, attendeeCount(users), booking.MemberID)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, capacityError(room, users, err, suggestions))
+			return
+		}
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	// 调整了日期、时间或参会人员时重新检查参会人员的会议冲突
+	var conflicts []attendeeConflict
+	if updated.Date != booking.Date || len(request.TimeSlots) > 0 || request.BookingUsers != nil {
+		users := booking.BookingUsers
+		if request.BookingUsers != nil {
+			users = request.BookingUsers
+		}
+		var owner models.Member
+		database.DB.First(&owner, booking.MemberID)
+		conflicts, err = findAttendeeConflicts(database.DB, users, &owner, updated.Date, window, booking.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check attendee conflicts"})
+			return
+		}
+		if request.BlockOwnConflict && hasOrganizerConflict(conflicts, &owner) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The organizer already has an overlapping booking", "attendee_conflicts": conflicts})
+			return
+		}
+		if len(conflicts) > 0 {
+			warnings = append(warnings, "Some attendees already have overlapping bookings")
+		}
+	}
+
+	// 检查时间段是否正在等待候补会员认领
+	if hold := findWaitlistHold(database.DB, updated.RoomID, updated.Date, window, booking.MemberID); hold != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": waitlistHoldError(hold).Error()})
+		return
+	}
+
+	// 检查时间段是否可用（排除本预定）
+	if !areSlotsAvailable(updated.RoomID, updated.Date, window, booking.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Some time slots are already booked"})
+		return
+	}
+
+	changes := diffBooking(&booking, &updated, request.BookingUsers)
+	if len(changes) == 0 {
+		c.JSON(http.StatusOK, booking)
+		return
+	}
+
+	// 配额检查、修改、变更记录和变更通知在同一事务中完成；
+	// 调整了会议室、日期或时间时按预定人的配额策略重新检查
+	member := middleware.CurrentMember(c)
+	token := middleware.Token(c)
+	// 新进入待审批或待审批的预定更换了会议室时，通知（新）会议室的管理员审批
+	awaitsApproval := updated.Status == "pending" && (booking.Status != "pending" || updated.RoomID != booking.RoomID)
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if rescheduled {
+			var owner models.Member
+			if err := tx.Preload("ManagedRooms").First(&owner, booking.MemberID).Error; err != nil {
+				return err
+			}
+			if err := enforceQuota(tx, room, &owner, updated.Date, window, booking.ID); err != nil {
+				return err
+			}
+		}
+		if err := applyBookingUpdate(tx, &updated, request.BookingUsers, changes, member.ID); err != nil {
+			return err
+		}
+		if err := notifyBookingChange(tx, token, &booking, &updated, nil, changes); err != nil {
+			return err
+		}
+		if awaitsApproval {
+			return notifyApprovalRequest(tx, token, &updated)
+		}
+		return nil
+	})
+	var exceeded *quotaExceededError
+	if errors.As(err, &exceeded) {
+		c.JSON(http.StatusBadRequest, quotaError(exceeded.policy, exceeded.violation))
+		return
+	}
+	if errors.Is(err, errSlotsTaken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Some time slots are already booked"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking"})
+		return
+	}
+	go outbox.Deliver()
+	go webhook.Deliver()
+
+	database.DB.Preload("Room").Preload("Member").Preload("BookingUsers").First(&updated, updated.ID)
+	if awaitsApproval {
+		warnings = append(warnings, "Booking is pending approval by the room admins")
+	}
+
+	// 改期或更换会议室后原时间段提供给候补会员
+	if updated.RoomID != booking.RoomID || updated.Date != booking.Date || updated.StartTime != booking.StartTime || updated.EndTime != booking.EndTime {
+		go processWaitlist(middleware.Token(c), booking.RoomID, booking.Date)
+	}
+
+	c.JSON(http.StatusOK, bookingResponse{Booking: updated, Warnings: warnings, SuggestedRooms: suggestions, AttendeeConflicts: conflicts})
 }
 
-// 生成所有时间段 (00:00 - 23:30)
-func generateAllTimeSlots() []models.TimeSlot {
-	var slots []models.TimeSlot
-	for hour := 0; hour < 24; hour++ {
-		for minute := 0; minute < 60; minute += 30 {
-			start := fmt.Sprintf("%02d:%02d", hour, minute)
-			end := getEndTime(start)
-			slots = append(slots, models.TimeSlot{
-				Start:    start,
-				End:      end,
-				IsBooked: false, // 默认未预定
-			})
+// 获取预定的变更记录，私密会议的预定理由和参会人员变更按查看权限隐藏
+func GetBookingChanges(c *gin.Context) {
+	var booking models.Booking
+	if err := database.DB.Preload("BookingUsers").First(&booking, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+	var changes []models.BookingChange
+	if err := database.DB.Where("booking_id = ?", booking.ID).Preload("Member").Order("id asc").Find(&changes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking changes"})
+		return
+	}
+	if redactBooking(middleware.CurrentMember(c), &booking) {
+		for i := range changes {
+			if changes[i].Field == "reason" || changes[i].Field == "attendees" {
+				changes[i].OldValue = ""
+				changes[i].NewValue = ""
+			}
 		}
 	}
-	return slots
+	c.JSON(http.StatusOK, changes)
+}
+
+// 比较预定修改前后的差异，users 为 nil 表示参会人员不变；私密会议的取值记录为 true 或 false，由消息模板按语言显示
+func diffBooking(old, updated *models.Booking, users []models.BookingUser) []models.BookingChange {
+	var changes []models.BookingChange
+	add := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			changes = append(changes, models.BookingChange{BookingID: old.ID, Field: field, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+
+	if old.RoomID != updated.RoomID {
+		add("room", old.Room.Name, updated.Room.Name)
+	}
+	add("date", old.Date, updated.Date)
+	add("time", old.StartTime+"-"+old.EndTime, updated.StartTime+"-"+updated.EndTime)
+	add("reason", old.Reason, updated.Reason)
+	add("privacy", strconv.FormatBool(old.IsPrivate), strconv.FormatBool(updated.IsPrivate))
+	if users != nil && !sameAttendees(old.BookingUsers, users) {
+		add("attendees", joinNicknames(old.BookingUsers), joinNicknames(users))
+	}
+	return changes
+}
+
+// 保存预定修改、重新占用时间段、替换参会人员、记录变更并发布预定修改事件，需在事务中调用
+func applyBookingUpdate(tx *gorm.DB, booking *models.Booking, users []models.BookingUser, changes []models.BookingChange, memberID uint) error {
+	if err := tx.Omit(clause.Associations).Save(booking).Error; err != nil {
+		return err
+	}
+	if err := releaseSlots(tx, booking.ID); err != nil {
+		return err
+	}
+	if err := reserveSlots(tx, booking); err != nil {
+		return err
+	}
+	if users != nil {
+		if err := replaceBookingUsers(tx, booking, users); err != nil {
+			return err
+		}
+	}
+	for _, change := range changes {
+		change.ID = 0
+		change.BookingID = booking.ID
+		change.MemberID = memberID
+		if err := tx.Create(&change).Error; err != nil {
+			return err
+		}
+	}
+	return webhook.Publish(tx, webhook.EventUpdated, booking.ID)
 }
 
-// 获取结束时间（增加30分钟）
-func getEndTime(startTime string) string {
-	parts := strings.Split(startTime, ":")
-	hour, _ := strconv.Atoi(parts[0])
-	minute, _ := strconv.Atoi(parts[1])
+// 在事务中写入会议变更通知，修改前后的参会人员和会议室管理员都会收到；修改周期预定时 series 为涉及的周期规则
+func notifyBookingChange(tx *gorm.DB, token string, old, updated *models.Booking, series *notify.Recurrence, changes []models.BookingChange) error {
+	var userIDs []int
+	for _, user := range old.BookingUsers {
+		userIDs = append(userIDs, int(user.Userid))
+	}
+	for _, user := range updated.BookingUsers {
+		userIDs = append(userIDs, int(user.Userid))
+	}
+	adminIDs := getRoomAdminIDs(updated.RoomID)
+	if old.RoomID != updated.RoomID {
+		adminIDs = append(adminIDs, getRoomAdminIDs(old.RoomID)...)
+	}
+
+	var items []notify.Change
+	for _, change := range changes {
+		items = append(items, notify.Change{Field: change.Field, Old: change.OldValue, New: change.NewValue})
+	}
 
-	minute += 30
-	if minute >= 60 {
-		minute -= 60
-		hour += 1
-		if hour >= 24 {
-			hour = 0
+	return outbox.Publish(tx, notify.Event{
+		Type:      "change",
+		UserIDs:   userIDs,
+		AdminIDs:  adminIDs,
+		Token:     token,
+		Date:      updated.Date,
+		StartTime: updated.StartTime,
+		EndTime:   updated.EndTime,
+		RoomName:  updated.Room.Name,
+		Reason:    updated.Reason,
+		Attendees: joinNicknames(updated.BookingUsers),
+		Series:    series,
+		Changes:   items,
+	})
+}
+
+// 判断两组参会人员是否相同（忽略顺序）
+func sameAttendees(a, b []models.BookingUser) bool {
+	set := make(map[uint]int)
+	for _, user := range a {
+		set[user.Userid]++
+	}
+	for _, user := range b {
+		set[user.Userid]--
+	}
+	for _, count := range set {
+		if count != 0 {
+			return false
 		}
 	}
+	return true
+}
 
-	return fmt.Sprintf("%02d:%02d", hour, minute)
+// 拼接参会人员昵称
+func joinNicknames(users []models.BookingUser) string {
+	var names []string
+	for _, user := range users {
+		names = append(names, user.Nickname)
+	}
+	return strings.Join(names, "、")
+}
+
+// 取消周期预定中本次及以后或全部的发生，并写入一条汇总的取消通知
+func cancelBookingSeries(c *gin.Context, booking models.Booking, scope string, cancelReason string) {
+	token := middleware.Token(c)
+	_, cancelled, err := cancelSeriesOccurrences(token, booking, scope, cancelReason)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel booking series"})
+		return
+	}
+	go outbox.Deliver()
+	go webhook.Deliver()
+
+	// 释放的时间段提供给候补会员
+	go func() {
+		for _, occurrence := range cancelled {
+			processWaitlist(token, occurrence.RoomID, occurrence.Date)
+		}
+	}()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Booking series cancelled successfully",
+		"cancelled": len(cancelled),
+	})
+}
+
+// 生成会议室在指定日期营业时间内的所有时间段
+func generateTimeSlots(room *models.Room, date time.Time) []models.TimeSlot {
+	var slots []models.TimeSlot
+	for _, window := range room.TimeSlotsOn(date) {
+		slots = append(slots, models.TimeSlot{
+			Start:    window.StartClock(),
+			End:      window.EndClock(),
+			IsBooked: false, // 默认未预定
+		})
+	}
+	return slots
 }
 
 // 过滤可用时间段
@@ -418,66 +985,25 @@ func filterAvailableSlots(allSlots []models.TimeSlot, bookings []models.Booking)
 
 // 检查时间段是否重叠
 func isTimeSlotOverlap(slot models.TimeSlot, booking models.Booking) bool {
-	// 处理跨越午夜的情况
-	slotStart := timeToMinutes(slot.Start)
-	slotEnd := timeToMinutes(slot.End)
-	bookingStart := timeToMinutes(booking.StartTime)
-	bookingEnd := timeToMinutes(booking.EndTime)
-
-	// 如果时间段跨越午夜，调整结束时间
-	if slotEnd == 0 && slotStart > 0 {
-		slotEnd = 24 * 60 // 24:00 转换为分钟
-	}
-	if bookingEnd == 0 && bookingStart > 0 {
-		bookingEnd = 24 * 60 // 24:00 转换为分钟
-	}
-
-	return slotStart < bookingEnd && slotEnd > bookingStart
+	return timeslot.MustWindow(slot.Start, slot.End).Overlaps(timeslot.MustWindow(booking.StartTime, booking.EndTime))
 }
 
-// 将时间字符串转换为分钟数
-func timeToMinutes(timeStr string) int {
-	parts := strings.Split(timeStr, ":")
-	hour, _ := strconv.Atoi(parts[0])
-	minute, _ := strconv.Atoi(parts[1])
-	return hour*60 + minute
-}
-
-// 检查时间段是否连续
-func areTimeSlotsConsecutive(timeSlots []string) bool {
-	if len(timeSlots) <= 1 {
-		return true
-	}
-
-	for i := 0; i < len(timeSlots)-1; i++ {
-		if getEndTime(timeSlots[i]) != timeSlots[i+1] {
-			return false
-		}
-	}
-
-	return true
-}
-
-// 检查时间段是否可用
-func areSlotsAvailable(roomID uint, date string, timeSlots []string) bool {
+// 检查时间区间是否可用，excludeIDs 中的预定不参与检查（用于修改已有预定）
+func areSlotsAvailable(roomID uint, date string, window timeslot.Window, excludeIDs ...uint) bool {
 	// 获取该日期该会议室的所有预定
 	var bookings []models.Booking
-	if err := database.DB.Where("room_id = ? AND date = ? AND status = ?", roomID, date, "active").Find(&bookings).Error; err != nil {
+	db := database.DB.Where("room_id = ? AND date = ? AND status IN ?", roomID, date, models.HoldingStatuses)
+	if len(excludeIDs) > 0 {
+		db = db.Where("id NOT IN ?", excludeIDs)
+	}
+	if err := db.Find(&bookings).Error; err != nil {
 		return false
 	}
 
-	// 检查每个时间段是否与现有预定重叠
-	for _, slotTime := range timeSlots {
-		slot := models.TimeSlot{
-			Start: slotTime,
-			End:   getEndTime(slotTime),
-		}
-
-		// 检查是否与任何预定重叠
-		for _, booking := range bookings {
-			if isTimeSlotOverlap(slot, booking) {
-				return false
-			}
+	// 检查是否与任何预定重叠
+	for _, booking := range bookings {
+		if window.Overlaps(timeslot.MustWindow(booking.StartTime, booking.EndTime)) {
+			return false
 		}
 	}
 
@@ -496,6 +1022,7 @@ func markBookedSlots(allSlots []models.TimeSlot, bookings []models.Booking) []mo
 		for _, booking := range bookings {
 			if isTimeSlotOverlap(slot, booking) {
 				slotCopy.IsBooked = true
+				slotCopy.IsPending = booking.Status == "pending"
 				break
 			}
 		}
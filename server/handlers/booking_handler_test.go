@@ -0,0 +1,89 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"roomly/database"
+	"roomly/models"
+	"roomly/routes"
+	"roomly/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+func request(t *testing.T, router http.Handler, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// 同一时间段的并发预定只有一个成功，其余因时间段已被占用而失败
+func TestCreateBookingConcurrent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testutil.OpenDB(t)
+	router := routes.SetupRoutes(testutil.Verifier{"alice": {DootaskID: 1, Nickname: "alice"}})
+
+	var room models.Room
+	if err := database.DB.Where("is_open = ?", true).First(&room).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 先创建会员，避免并发请求同时创建同一会员
+	if recorder := request(t, router, http.MethodGet, "/api/members/me", "alice", nil); recorder.Code != http.StatusOK {
+		t.Fatalf("GET /api/members/me = %d: %s", recorder.Code, recorder.Body)
+	}
+
+	body := models.BookingRequest{
+		RoomID:       room.ID,
+		Date:         time.Now().AddDate(0, 0, 1).Format("2006-01-02"),
+		TimeSlots:    []string{"10:00", "10:30"},
+		Reason:       "项目周会",
+		BookingUsers: []models.BookingUser{{Userid: 1, Nickname: "alice"}},
+	}
+	const requests = 10
+	codes := make([]int, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = request(t, router, http.MethodPost, "/api/bookings", "alice", body).Code
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusCreated:
+			created++
+		case http.StatusBadRequest:
+		default:
+			t.Errorf("unexpected status %d", code)
+		}
+	}
+	if created != 1 {
+		t.Errorf("created %d bookings, want 1 (statuses %v)", created, codes)
+	}
+
+	var count int64
+	database.DB.Model(&models.Booking{}).Where("room_id = ? AND date = ?", room.ID, body.Date).Count(&count)
+	if count != 1 {
+		t.Errorf("stored %d bookings, want 1", count)
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+
+	"roomly/models"
+	"roomly/webhook"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errSlotsTaken 时间段已被其他有效预定占用
+var errSlotsTaken = errors.New("some time slots are already booked")
+
+// 创建预定、参会人员、占用时间段并发布预定创建事件，需在事务中调用以保证原子性
+func insertBooking(tx *gorm.DB, booking *models.Booking, users []models.BookingUser) error {
+	if err := tx.Omit(clause.Associations).Create(booking).Error; err != nil {
+		return err
+	}
+	if err := reserveSlots(tx, booking); err != nil {
+		return err
+	}
+	if err := replaceBookingUsers(tx, booking, users); err != nil {
+		return err
+	}
+	return webhook.Publish(tx, webhook.EventCreated, booking.ID)
+}
+
+// 替换预定的参会人员
+func replaceBookingUsers(tx *gorm.DB, booking *models.Booking, users []models.BookingUser) error {
+	if err := tx.Where("booking_id = ?", booking.ID).Delete(&models.BookingUser{}).Error; err != nil {
+		return err
+	}
+	booking.BookingUsers = nil
+	for _, user := range users {
+		bookingUser := models.BookingUser{
+			BookingID: booking.ID,
+			Userid:    user.Userid,
+			Nickname:  user.Nickname,
+		}
+		if err := tx.Create(&bookingUser).Error; err != nil {
+			return err
+		}
+		booking.BookingUsers = append(booking.BookingUsers, bookingUser)
+	}
+	return nil
+}
+
+// 占用预定的时间段，唯一索引冲突时返回 errSlotsTaken
+func reserveSlots(tx *gorm.DB, booking *models.Booking) error {
+	slots := models.OccupiedSlots(booking)
+	if len(slots) == 0 {
+		return nil
+	}
+	if err := tx.Create(&slots).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return errSlotsTaken
+		}
+		return err
+	}
+	return nil
+}
+
+// 释放预定占用的时间段
+func releaseSlots(tx *gorm.DB, bookingIDs ...uint) error {
+	if len(bookingIDs) == 0 {
+		return nil
+	}
+	return tx.Where("booking_id IN ?", bookingIDs).Delete(&models.BookingSlot{}).Error
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"roomly/database"
+	"roomly/holiday"
+	"roomly/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 获取节假日日历，默认返回当年的记录
+func GetCalendarDays(c *gin.Context) {
+	year := time.Now().Format("2006")
+	startDate := c.DefaultQuery("start_date", year+"-01-01")
+	endDate := c.DefaultQuery("end_date", year+"-12-31")
+
+	var days []models.CalendarDay
+	if err := database.DB.Where("date BETWEEN ? AND ?", startDate, endDate).Order("date asc").Find(&days).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch calendar"})
+		return
+	}
+	c.JSON(http.StatusOK, days)
+}
+
+// 设置某一天为节假日或调休工作日，已存在的记录会被覆盖
+func SetCalendarDay(c *gin.Context) {
+	date := c.Param("date")
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+		return
+	}
+
+	var request struct {
+		Kind string `json:"kind" binding:"required"`
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !holiday.IsValidKind(request.Kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be holiday or workday"})
+		return
+	}
+
+	day := models.CalendarDay{Date: date, Kind: request.Kind, Name: request.Name}
+	if err := saveCalendarDays(database.DB, []models.CalendarDay{day}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save calendar day"})
+		return
+	}
+	database.DB.Where("date = ?", date).First(&day)
+	c.JSON(http.StatusOK, day)
+}
+
+// 删除某一天的日历记录，恢复为默认的工作日/周末
+func DeleteCalendarDay(c *gin.Context) {
+	result := database.DB.Where("date = ?", c.Param("date")).Delete(&models.CalendarDay{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calendar day"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar day not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar day deleted successfully"})
+}
+
+// 从 ICS 或 CSV 文件导入节假日日历，同一天的记录会被覆盖；
+// 文件格式由 format 参数（ics、csv）或文件扩展名决定
+func ImportCalendar(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	format := strings.ToLower(c.DefaultQuery("format", strings.TrimPrefix(filepath.Ext(fileHeader.Filename), ".")))
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	var entries []holiday.Entry
+	switch format {
+	case "ics", "ical":
+		entries, err = holiday.ParseICS(file)
+	case "csv":
+		entries, err = holiday.ParseCSV(file)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be ics or csv"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to parse calendar: %v", err)})
+		return
+	}
+	if len(entries) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No calendar entries found in file"})
+		return
+	}
+
+	days := make([]models.CalendarDay, 0, len(entries))
+	for _, entry := range entries {
+		days = append(days, models.CalendarDay{Date: entry.Date, Kind: entry.Kind, Name: entry.Name})
+	}
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return saveCalendarDays(tx, days)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import calendar"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Calendar imported successfully",
+		"imported": len(days),
+	})
+}
+
+// 按日期写入日历记录，已存在的日期更新类型和名称
+func saveCalendarDays(db *gorm.DB, days []models.CalendarDay) error {
+	for i := range days {
+		err := db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "date"}},
+			DoUpdates: clause.AssignmentColumns([]string{"kind", "name", "updated_at"}),
+		}).Create(&days[i]).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 判断指定日期是否为非工作日，返回节假日名称（周末返回“周末”）
+func nonWorkingDay(db *gorm.DB, date string) (bool, string) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return false, ""
+	}
+	var record models.CalendarDay
+	if err := db.Where("date = ?", date).First(&record).Error; err == nil {
+		return calendarNonWorkingDay(day, &record)
+	}
+	return calendarNonWorkingDay(day, nil)
+}
+
+// 根据当天的日历记录判断是否为非工作日，没有记录时按周末判断
+func calendarNonWorkingDay(day time.Time, record *models.CalendarDay) (bool, string) {
+	if record != nil {
+		entry := holiday.Entry{Date: record.Date, Kind: record.Kind, Name: record.Name}
+		return !holiday.IsWorkingDay(day, &entry), record.Name
+	}
+	return !holiday.IsWorkingDay(day, nil), "周末"
+}
+
+// 按会议室的非工作日策略检查预定日期：reject 时返回错误，warn 时返回提示信息
+func checkHoliday(db *gorm.DB, room *models.Room, date string) (string, error) {
+	if room.HolidayPolicy == models.HolidayAllow {
+		return "", nil
+	}
+	closed, name := nonWorkingDay(db, date)
+	if !closed {
+		return "", nil
+	}
+	if room.HolidayPolicy == models.HolidayReject {
+		return "", fmt.Errorf("Room does not accept bookings on non-working days (%s %s)", date, name)
+	}
+	return fmt.Sprintf("%s is a non-working day (%s)", date, name), nil
+}
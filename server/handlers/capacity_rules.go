@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"roomly/database"
+	"roomly/models"
+	"roomly/policy"
+	"roomly/timeslot"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 超员时最多推荐的会议室数量
+const maxSuggestedRooms = 5
+
+// 去重后的参会人数，同一用户重复出现只计一次
+func attendeeCount(users []models.BookingUser) int {
+	seen := make(map[uint]bool)
+	for _, user := range users {
+		seen[user.Userid] = true
+	}
+	return len(seen)
+}
+
+// 按会议室的超员策略检查参会人数：reject 时返回错误，override 时需会议室管理员确认才能超员，
+// warn 或已确认超员时返回提示信息
+func checkCapacity(room *models.Room, member *models.Member, users []models.BookingUser, override bool) (string, error) {
+	attendees := attendeeCount(users)
+	if room.Capacity <= 0 || attendees <= room.Capacity {
+		return "", nil
+	}
+	switch room.CapacityPolicy {
+	case models.CapacityReject:
+		return "", fmt.Errorf("Attendee count %d exceeds room capacity %d", attendees, room.Capacity)
+	case models.CapacityOverride:
+		if !override || policy.CanManageRoom(member, room.ID) != nil {
+			return "", fmt.Errorf("Attendee count %d exceeds room capacity %d, a room admin override is required", attendees, room.Capacity)
+		}
+		return fmt.Sprintf("Attendee count %d exceeds room capacity %d (overridden by room admin)", attendees, room.Capacity), nil
+	}
+	return fmt.Sprintf("Attendee count %d exceeds room capacity %d", attendees, room.Capacity), nil
+}
+
+// 超员的错误响应，附带参会人数、会议室容量和同一时间空闲的更大会议室
+func capacityError(room *models.Room, users []models.BookingUser, err error, suggestions []models.Room) gin.H {
+	if suggestions == nil {
+		suggestions = []models.Room{}
+	}
+	return gin.H{
+		"error":           err.Error(),
+		"attendees":       attendeeCount(users),
+		"capacity":        room.Capacity,
+		"suggested_rooms": suggestions,
+	}
+}
+
+// 查找同一时间空闲且能容纳所有参会人员的其他开放会议室，按容量从小到大排列
+func largerFreeRooms(room *models.Room, date string, window timeslot.Window, attendees int, memberID uint) []models.Room {
+	suggestions := []models.Room{}
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return suggestions
+	}
+
+	var candidates []models.Room
+	if err := preloadRoomDetails(database.DB).Where("is_open = ? AND id <> ? AND capacity >= ?", true, room.ID, attendees).
+		Order("capacity asc, id asc").Find(&candidates).Error; err != nil || len(candidates) == 0 {
+		return suggestions
+	}
+	var roomIDs []uint
+	for _, candidate := range candidates {
+		roomIDs = append(roomIDs, candidate.ID)
+	}
+	occupancy, err := loadRoomOccupancy(roomIDs, date, date, memberID)
+	if err != nil {
+		return suggestions
+	}
+
+	for i := range candidates {
+		candidate := &candidates[i]
+		if checkBookingWindow(candidate, day, window) != nil {
+			continue
+		}
+		if !isWindowFree(occupancy.slots(candidate, date), window) {
+			continue
+		}
+		suggestions = append(suggestions, *candidate)
+		if len(suggestions) == maxSuggestedRooms {
+			break
+		}
+	}
+	return suggestions
+}
+
+// 判断区间是否空闲，与区间部分重叠的已占用时间段也视为冲突
+func isWindowFree(slots []models.TimeSlot, window timeslot.Window) bool {
+	for _, slot := range slots {
+		if slot.IsBooked && window.Overlaps(timeslot.MustWindow(slot.Start, slot.End)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"roomly/database"
+	"roomly/middleware"
+	"roomly/models"
+	"roomly/notify"
+	"roomly/outbox"
+	"roomly/policy"
+	"roomly/timeslot"
+	"roomly/webhook"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 会议开始前最早可以签到的分钟数
+const checkInEarlyMinutes = 15
+
+// 签到，预定人或任一参会人员签到即可；会议开始前15分钟至会议结束前可以签到
+func CheckInBooking(c *gin.Context) {
+	id := c.Param("id")
+
+	var booking models.Booking
+	if err := database.DB.Preload("BookingUsers").Preload("Room").First(&booking, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	member := middleware.CurrentMember(c)
+	if err := policy.CanCheckIn(member, &booking); err != nil {
+		middleware.AbortWithPolicyError(c, err)
+		return
+	}
+	if booking.Status != "active" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only active bookings can be checked in"})
+		return
+	}
+	// 幂等性校验
+	if booking.CheckedInAt != nil {
+		c.JSON(http.StatusOK, booking)
+		return
+	}
+
+	now := time.Now()
+	if now.Before(bookingClock(booking.Date, booking.StartTime).Add(-checkInEarlyMinutes * time.Minute)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Check-in opens %d minutes before the meeting starts", checkInEarlyMinutes)})
+		return
+	}
+	if !now.Before(bookingClock(booking.Date, booking.EndTime)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The meeting has already ended"})
+		return
+	}
+
+	// 只更新仍未签到且有效的预定，避免与未签到释放任务互相覆盖
+	result := database.DB.Model(&models.Booking{}).
+		Where("id = ? AND status = ? AND checked_in_at IS NULL", booking.ID, "active").
+		Updates(map[string]interface{}{"checked_in_at": now, "checked_in_by": member.DootaskID})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check in"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking has been released"})
+		return
+	}
+
+	database.DB.Preload("Room").Preload("Member").Preload("BookingUsers").First(&booking, booking.ID)
+	c.JSON(http.StatusOK, booking)
+}
+
+// 获取会员的未签到次数和最近的未签到预定
+func GetMemberNoShows(c *gin.Context) {
+	memberID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid member ID"})
+		return
+	}
+	if err := policy.CanViewMember(middleware.CurrentMember(c), uint(memberID)); err != nil {
+		middleware.AbortWithPolicyError(c, err)
+		return
+	}
+
+	var count int64
+	if err := database.DB.Model(&models.Booking{}).Where("member_id = ? AND status = ?", memberID, "no_show").Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count no-shows"})
+		return
+	}
+	var bookings []models.Booking
+	database.DB.Where("member_id = ? AND status = ?", memberID, "no_show").Preload("Room").Order("date desc, start_time desc").Limit(20).Find(&bookings)
+
+	c.JSON(http.StatusOK, gin.H{
+		"member_id":     memberID,
+		"no_show_count": count,
+		"bookings":      bookings,
+	})
+}
+
+// 未签到统计行
+type noShowStat struct {
+	MemberID    uint   `json:"member_id"`
+	Name        string `json:"name"`
+	DootaskID   uint   `json:"dootask_id"`
+	NoShowCount int64  `json:"no_show_count"`
+}
+
+// 按会员统计未签到次数，支持 start_date、end_date 筛选
+func GetNoShowStats(c *gin.Context) {
+	db := database.DB.Table("bookings b").
+		Select("m.id as member_id, m.name, m.dootask_id, COUNT(b.id) as no_show_count").
+		Joins("JOIN members m ON m.id = b.member_id").
+		Where("b.status = ?", "no_show")
+	if startDate := c.Query("start_date"); startDate != "" {
+		db = db.Where("b.date >= ?", startDate)
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		db = db.Where("b.date <= ?", endDate)
+	}
+
+	var stats []noShowStat
+	if err := db.Group("m.id, m.name, m.dootask_id").Order("no_show_count desc").Scan(&stats).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch no-show statistics"})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// 定时任务：会议开始超过宽限时间仍未签到的预定标记为 no_show，释放时间段并通知预定人；
+// 宽限时间不短于会议时长时以会议结束时间为准，保证预定在过期前被记录为未签到
+func ReleaseNoShowBookings() {
+	now := time.Now()
+
+	var bookings []models.Booking
+	database.DB.Joins("Room").Preload("Member").Preload("BookingUsers").
+		Where("bookings.status = ? AND bookings.checked_in_at IS NULL AND bookings.date <= ?", "active", now.Format("2006-01-02")).
+		Where("Room.check_in_grace > 0").
+		Find(&bookings)
+
+	for _, booking := range bookings {
+		deadline := bookingClock(booking.Date, booking.StartTime).Add(time.Duration(booking.Room.CheckInGrace) * time.Minute)
+		if end := bookingClock(booking.Date, booking.EndTime); end.Before(deadline) {
+			deadline = end
+		}
+		if now.Before(deadline) {
+			continue
+		}
+
+		token := models.SystemToken()
+		var released bool
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&models.Booking{}).
+				Where("id = ? AND status = ? AND checked_in_at IS NULL", booking.ID, "active").
+				Update("status", "no_show")
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			released = true
+			if err := releaseSlots(tx, booking.ID); err != nil {
+				return err
+			}
+			if err := webhook.Publish(tx, webhook.EventNoShow, booking.ID); err != nil {
+				return err
+			}
+			if token == "" {
+				return nil
+			}
+			return outbox.Publish(tx, notify.Event{
+				Type:         "no_show",
+				OrganizerIDs: []int{int(booking.Member.DootaskID)},
+				Token:        token,
+				Date:         booking.Date,
+				StartTime:    booking.StartTime,
+				EndTime:      booking.EndTime,
+				RoomName:     booking.Room.Name,
+				Reason:       booking.Reason,
+				Attendees:    joinNicknames(booking.BookingUsers),
+				Content:      []string{strconv.Itoa(booking.Room.CheckInGrace)},
+			})
+		})
+		if err != nil {
+			fmt.Printf("释放未签到预定%d失败: %v\n", booking.ID, err)
+			continue
+		}
+		if !released {
+			continue
+		}
+		if token == "" {
+			fmt.Printf("未配置 DOOTASK_BOT_TOKEN，跳过未签到通知: 预定%d\n", booking.ID)
+		}
+
+		// 释放的时间段提供给候补会员
+		processWaitlist(token, booking.RoomID, booking.Date)
+	}
+}
+
+// 将预定的日期和时间转换为本地时间，24:00 表示次日零点
+func bookingClock(date, clock string) time.Time {
+	day, _ := time.ParseInLocation("2006-01-02", date, time.Local)
+	minutes, _ := timeslot.Parse(clock)
+	return day.Add(time.Duration(minutes) * time.Minute)
+}
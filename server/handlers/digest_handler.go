@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"roomly/database"
+	"roomly/models"
+	"roomly/notify"
+	"roomly/outbox"
+	"roomly/policy"
+)
+
+// 每周会议安排包含的天数
+const agendaDays = 7
+
+// 定时任务：给订阅了每日汇总的会议室管理员发送所管理会议室次日的预定汇总，没有预定时不发送
+func SendDailyDigests() {
+	token := models.SystemToken()
+	if token == "" {
+		log.Printf("未配置 DOOTASK_BOT_TOKEN，跳过每日预定汇总")
+		return
+	}
+
+	var preferences []models.MemberPreference
+	database.DB.Where("daily_digest = ?", true).Find(&preferences)
+	if len(preferences) == 0 {
+		return
+	}
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	for _, preference := range preferences {
+		var member models.Member
+		if err := database.DB.Preload("ManagedRooms").First(&member, preference.MemberID).Error; err != nil {
+			continue
+		}
+		// 已不是会议室管理员的会员不再发送
+		if policy.RequireRole(&member, policy.RoleRoomAdmin) != nil || member.DootaskID == 0 {
+			continue
+		}
+
+		db := database.DB.Preload("Room").Preload("Member").
+			Where("date = ? AND status = ?", tomorrow, "active")
+		if roomIDs := policy.ManagedRoomIDs(&member); roomIDs != nil {
+			if len(roomIDs) == 0 {
+				continue
+			}
+			db = db.Where("room_id IN ?", roomIDs)
+		}
+		var bookings []models.Booking
+		if err := db.Order("start_time asc, room_id asc").Find(&bookings).Error; err != nil || len(bookings) == 0 {
+			continue
+		}
+
+		var lines []string
+		for _, booking := range bookings {
+			lines = append(lines, agendaLine(booking.StartTime+"-"+booking.EndTime, booking.Room.Name, booking.Reason, booking.Member.Name))
+		}
+		err := outbox.Publish(database.DB, notify.Event{
+			Type:     "daily_digest",
+			AdminIDs: []int{int(member.DootaskID)},
+			Token:    token,
+			Date:     tomorrow,
+			Content:  lines,
+		})
+		if err != nil {
+			log.Printf("写入每日预定汇总失败: 会员%d: %v", member.ID, err)
+		}
+	}
+}
+
+// 定时任务：给每位会员发送接下来一周作为参会人员参加的会议，没有会议时不发送
+func SendWeeklyAgendas() {
+	token := models.SystemToken()
+	if token == "" {
+		log.Printf("未配置 DOOTASK_BOT_TOKEN，跳过每周会议安排")
+		return
+	}
+
+	now := time.Now()
+	startDate := now.Format("2006-01-02")
+	endDate := now.AddDate(0, 0, agendaDays-1).Format("2006-01-02")
+	var bookings []models.Booking
+	database.DB.Preload("Room").Preload("BookingUsers").
+		Where("date BETWEEN ? AND ? AND status = ?", startDate, endDate, "active").
+		Order("date asc, start_time asc").Find(&bookings)
+	if len(bookings) == 0 {
+		return
+	}
+
+	// 按参会人员汇总，同一会议中重复的参会人员只列出一次
+	agendas := make(map[uint][]string)
+	for _, booking := range bookings {
+		seen := make(map[uint]bool)
+		for _, user := range booking.BookingUsers {
+			if seen[user.Userid] {
+				continue
+			}
+			seen[user.Userid] = true
+			agendas[user.Userid] = append(agendas[user.Userid], agendaLine(booking.Date+" "+booking.StartTime+"-"+booking.EndTime, booking.Room.Name, booking.Reason))
+		}
+	}
+
+	var members []models.Member
+	database.DB.Where("dootask_id > 0").Order("id asc").Find(&members)
+	for _, member := range members {
+		lines := agendas[member.DootaskID]
+		if len(lines) == 0 {
+			continue
+		}
+		err := outbox.Publish(database.DB, notify.Event{
+			Type:    "weekly_agenda",
+			UserIDs: []int{int(member.DootaskID)},
+			Token:   token,
+			Date:    startDate + " ~ " + endDate,
+			Content: lines,
+		})
+		if err != nil {
+			log.Printf("写入每周会议安排失败: 会员%d: %v", member.ID, err)
+		}
+	}
+}
+
+// 汇总中的一行，各项用 | 分隔，空项省略
+func agendaLine(parts ...string) string {
+	var values []string
+	for _, part := range parts {
+		if part != "" {
+			values = append(values, part)
+		}
+	}
+	return strings.Join(values, " | ")
+}
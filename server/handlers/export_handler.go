@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"roomly/database"
+	"roomly/middleware"
 	"roomly/models"
+	"roomly/policy"
 
 	"github.com/gin-gonic/gin"
 	"github.com/tealeg/xlsx"
@@ -34,6 +36,10 @@ func ExportBookings(c *gin.Context) {
 	if roomID != "" {
 		query = query.Where("room_id = ?", roomID)
 	}
+	// 会议室管理员只能导出自己管理的会议室
+	if roomIDs := policy.ManagedRoomIDs(middleware.CurrentMember(c)); roomIDs != nil {
+		query = query.Where("room_id IN ?", roomIDs)
+	}
 	if memberID != "" {
 		query = query.Where("member_id = ?", memberID)
 	}
@@ -104,6 +110,12 @@ func ExportBookings(c *gin.Context) {
 		statusText := "有效"
 		if booking.Status == "cancelled" {
 			statusText = "已取消"
+		} else if booking.Status == "no_show" {
+			statusText = "未签到"
+		} else if booking.Status == "pending" {
+			statusText = "待审批"
+		} else if booking.Status == "rejected" {
+			statusText = "已拒绝"
 		}
 		row.AddCell().SetString(statusText)
 
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"roomly/database"
+	"roomly/middleware"
+	"roomly/models"
+	"roomly/policy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 时间轴一次最多覆盖的天数
+const maxGridDays = 7
+
+// 时间轴格子中占用该时间段的预定信息，私密会议对无权查看的会员隐藏预定理由和参会人员
+type gridBooking struct {
+	ID           uint                 `json:"id"`
+	Status       string               `json:"status"`
+	StartTime    string               `json:"start_time"`
+	EndTime      string               `json:"end_time"`
+	MemberID     uint                 `json:"member_id"`
+	MemberName   string               `json:"member_name"`
+	Reason       string               `json:"reason"`
+	IsPrivate    bool                 `json:"is_private"`
+	BookingUsers []models.BookingUser `json:"booking_users"`
+}
+
+// 时间轴格子
+type gridCell struct {
+	models.TimeSlot
+	Booking *gridBooking `json:"booking,omitempty"`
+}
+
+// 会议室某天的时间轴
+type gridDay struct {
+	Date        string     `json:"date"`
+	IsHoliday   bool       `json:"is_holiday,omitempty"`
+	HolidayName string     `json:"holiday_name,omitempty"`
+	Slots       []gridCell `json:"slots"`
+}
+
+// 会议室在日期范围内的时间轴
+type gridRow struct {
+	Room models.Room `json:"room"`
+	Days []gridDay   `json:"days"`
+}
+
+// 获取多个会议室在一天或一周内的时间轴：date 或 start_date/end_date，room_ids 为逗号分隔的会议室 ID，默认所有开放的会议室
+func GetRoomGrid(c *gin.Context) {
+	startDate := c.Query("start_date")
+	endDate := c.Query("end_date")
+	if date := c.Query("date"); date != "" {
+		startDate, endDate = date, date
+	}
+	if startDate == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date or start_date is required"})
+		return
+	}
+	if endDate == "" {
+		endDate = startDate
+	}
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+		return
+	}
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+		return
+	}
+	if end.Before(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must not be before start_date"})
+		return
+	}
+	if end.Sub(start) >= maxGridDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Grid range must not exceed 7 days"})
+		return
+	}
+
+	db := preloadRoomDetails(database.DB).Order("id asc")
+	if roomIDsParam := c.Query("room_ids"); roomIDsParam != "" {
+		var ids []uint
+		for _, value := range strings.Split(roomIDsParam, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room_ids"})
+				return
+			}
+			ids = append(ids, uint(id))
+		}
+		db = db.Where("id IN ?", ids)
+	} else {
+		db = db.Where("is_open = ?", true)
+	}
+	var rooms []models.Room
+	if err := db.Find(&rooms).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rooms"})
+		return
+	}
+	rows := []gridRow{}
+	if len(rooms) == 0 {
+		c.JSON(http.StatusOK, rows)
+		return
+	}
+	var roomIDs []uint
+	for _, room := range rooms {
+		roomIDs = append(roomIDs, room.ID)
+	}
+
+	member := middleware.CurrentMember(c)
+	occupancy, err := loadRoomOccupancy(roomIDs, startDate, endDate, member.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+		return
+	}
+
+	for i := range rooms {
+		room := &rooms[i]
+		row := gridRow{Room: *room}
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			date := day.Format("2006-01-02")
+			timeline := gridDay{Date: date}
+			if room.HolidayPolicy != models.HolidayAllow {
+				timeline.IsHoliday, timeline.HolidayName = occupancy.nonWorkingDay(date)
+			}
+
+			bookings := occupancy.bookingsOn(room.ID, date)
+			for _, slot := range occupancy.slots(room, date) {
+				cell := gridCell{TimeSlot: slot}
+				for j := range bookings {
+					if isTimeSlotOverlap(slot, bookings[j]) {
+						cell.Booking = newGridBooking(member, &bookings[j])
+						break
+					}
+				}
+				timeline.Slots = append(timeline.Slots, cell)
+			}
+			row.Days = append(row.Days, timeline)
+		}
+		rows = append(rows, row)
+	}
+
+	c.JSON(http.StatusOK, rows)
+}
+
+// 生成时间轴中的预定信息，按查看权限隐藏私密会议的详情
+func newGridBooking(member *models.Member, booking *models.Booking) *gridBooking {
+	info := &gridBooking{
+		ID:           booking.ID,
+		Status:       booking.Status,
+		StartTime:    booking.StartTime,
+		EndTime:      booking.EndTime,
+		MemberID:     booking.MemberID,
+		MemberName:   booking.Member.Name,
+		Reason:       booking.Reason,
+		IsPrivate:    booking.IsPrivate,
+		BookingUsers: booking.BookingUsers,
+	}
+	if policy.CanViewBookingDetails(member, booking) != nil {
+		info.Reason = ""
+		info.BookingUsers = []models.BookingUser{}
+	}
+	return info
+}
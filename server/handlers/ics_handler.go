@@ -0,0 +1,470 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"roomly/database"
+	"roomly/ics"
+	"roomly/middleware"
+	"roomly/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 日历订阅类型
+const (
+	feedKindMember  = "member"
+	feedKindRoom    = "room"
+	feedKindBooking = "booking"
+)
+
+// 订阅中包含多少天前的预定
+const feedHistoryDays = 90
+
+// 建议日历客户端刷新订阅的间隔
+const feedRefresh = 15 * time.Minute
+
+// 日历事件 UID 的域名部分，同一预定在下载和订阅中的 UID 保持不变
+const uidDomain = "roomly"
+
+// 日历订阅及其地址，webcal_url 可直接在日历客户端中打开订阅
+type calendarFeedResponse struct {
+	models.CalendarFeed
+	URL       string `json:"url"`
+	WebcalURL string `json:"webcal_url"`
+}
+
+// 下载单个预定的 iCalendar 文件，私密会议的详情按查看权限隐藏
+func ExportBookingICS(c *gin.Context) {
+	var booking models.Booking
+	if err := database.DB.Preload("Room").Preload("Member").Preload("BookingUsers").First(&booking, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	calendar := ics.Calendar{Location: ics.LocalLocation(), Events: []ics.Event{bookingEvent(&booking, middleware.CurrentMember(c))}}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=booking-%d.ics", booking.ID))
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", calendar.Encode())
+}
+
+// 获取当前会员的日历订阅
+func GetCalendarFeeds(c *gin.Context) {
+	member := middleware.CurrentMember(c)
+	var feeds []models.CalendarFeed
+	if err := database.DB.Preload("Room").
+		Where("member_id = ? AND kind IN ?", member.ID, []string{feedKindMember, feedKindRoom}).
+		Order("id asc").Find(&feeds).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch calendar feeds"})
+		return
+	}
+
+	responses := []calendarFeedResponse{}
+	for _, feed := range feeds {
+		responses = append(responses, newCalendarFeedResponse(c, feed))
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+// 创建日历订阅：指定 room_id 时订阅会议室的所有预定，否则订阅当前会员参加的会议；已有相同订阅时直接返回
+func CreateCalendarFeed(c *gin.Context) {
+	var request struct {
+		RoomID *uint `json:"room_id"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member := middleware.CurrentMember(c)
+	db := database.DB.Where("member_id = ?", member.ID)
+	feed := models.CalendarFeed{Kind: feedKindMember, MemberID: member.ID}
+	if request.RoomID != nil {
+		var room models.Room
+		if err := database.DB.First(&room, *request.RoomID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+			return
+		}
+		feed.Kind = feedKindRoom
+		feed.RoomID = &room.ID
+		db = db.Where("kind = ? AND room_id = ?", feedKindRoom, room.ID)
+	} else {
+		db = db.Where("kind = ?", feedKindMember)
+	}
+
+	var existing []models.CalendarFeed
+	if err := db.Preload("Room").Limit(1).Find(&existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar feed"})
+		return
+	}
+	if len(existing) > 0 {
+		c.JSON(http.StatusOK, newCalendarFeedResponse(c, existing[0]))
+		return
+	}
+
+	feed.Token = newFeedToken()
+	if err := database.DB.Create(&feed).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar feed"})
+		return
+	}
+	database.DB.Preload("Room").First(&feed, feed.ID)
+	c.JSON(http.StatusCreated, newCalendarFeedResponse(c, feed))
+}
+
+// 删除日历订阅，订阅地址随即失效；需要更换地址时删除后重新创建
+func DeleteCalendarFeed(c *gin.Context) {
+	result := database.DB.Where("member_id = ?", middleware.CurrentMember(c).ID).Delete(&models.CalendarFeed{}, c.Param("id"))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calendar feed"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar feed deleted successfully"})
+}
+
+// 日历客户端获取订阅内容，无需登录，凭订阅地址中的 token 访问；私密会议按创建订阅的会员的权限显示
+func GetCalendarFeedICS(c *gin.Context) {
+	var feed models.CalendarFeed
+	if err := database.DB.Preload("Room").Where("token = ?", c.Param("token")).First(&feed).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+		return
+	}
+	var owner models.Member
+	if err := database.DB.Preload("ManagedRooms").First(&owner, feed.MemberID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+		return
+	}
+
+	calendar, err := feedCalendar(&feed, &owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+		return
+	}
+	database.DB.Model(&feed).UpdateColumn("last_accessed_at", time.Now())
+
+	c.Header("Content-Disposition", "inline; filename=calendar.ics")
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", calendar.Encode())
+}
+
+// 在事务中获取预定的日历文件地址，附在会议通知中；没有配置 PUBLIC_URL 时返回空
+func bookingCalendarURL(tx *gorm.DB, booking *models.Booking) (string, error) {
+	base := publicURL()
+	if base == "" {
+		return "", nil
+	}
+	var feeds []models.CalendarFeed
+	if err := tx.Where("kind = ? AND booking_id = ?", feedKindBooking, booking.ID).Limit(1).Find(&feeds).Error; err != nil {
+		return "", err
+	}
+	if len(feeds) == 0 {
+		feed := models.CalendarFeed{Token: newFeedToken(), Kind: feedKindBooking, MemberID: booking.MemberID, BookingID: &booking.ID}
+		if err := tx.Create(&feed).Error; err != nil {
+			return "", err
+		}
+		feeds = append(feeds, feed)
+	}
+	return base + feedPath(feeds[0].Token), nil
+}
+
+// 生成订阅的日历：单个预定的订阅只包含该预定，其余订阅包含最近 feedHistoryDays 天以来的预定，周期预定合并为周期事件
+func feedCalendar(feed *models.CalendarFeed, owner *models.Member) (*ics.Calendar, error) {
+	db := database.DB.Preload("Room").Preload("Member").Preload("BookingUsers")
+	calendar := &ics.Calendar{Refresh: feedRefresh, Location: ics.LocalLocation()}
+	switch feed.Kind {
+	case feedKindBooking:
+		var bookings []models.Booking
+		if feed.BookingID != nil {
+			if err := db.Where("id = ?", *feed.BookingID).Find(&bookings).Error; err != nil {
+				return nil, err
+			}
+		}
+		for i := range bookings {
+			calendar.Name = bookings[i].Reason
+			calendar.Events = append(calendar.Events, bookingEvent(&bookings[i], owner))
+		}
+		return calendar, nil
+	case feedKindRoom:
+		if feed.RoomID == nil || feed.Room == nil {
+			return calendar, nil
+		}
+		calendar.Name = feed.Room.Name
+		db = db.Where("room_id = ?", *feed.RoomID)
+	default:
+		calendar.Name = owner.Name + " - Roomly"
+		attending := database.DB.Model(&models.BookingUser{}).Select("booking_id").Where("userid = ?", owner.DootaskID)
+		db = db.Where("member_id = ? OR id IN (?)", owner.ID, attending)
+	}
+
+	from := time.Now().AddDate(0, 0, -feedHistoryDays).Format("2006-01-02")
+	var bookings []models.Booking
+	if err := db.Where("date >= ?", from).Order("date asc, start_time asc, id asc").Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+	events, err := bookingEvents(bookings, owner)
+	if err != nil {
+		return nil, err
+	}
+	sortEvents(events)
+	calendar.Events = events
+	return calendar, nil
+}
+
+// 将预定转换为日历事件，同一周期预定的预定合并为一个周期事件
+func bookingEvents(bookings []models.Booking, viewer *models.Member) ([]ics.Event, error) {
+	var events []ics.Event
+	included := make(map[uint]map[uint]bool)
+	var seriesIDs []uint
+	for i := range bookings {
+		booking := &bookings[i]
+		if booking.SeriesID == nil {
+			events = append(events, bookingEvent(booking, viewer))
+			continue
+		}
+		if included[*booking.SeriesID] == nil {
+			included[*booking.SeriesID] = make(map[uint]bool)
+			seriesIDs = append(seriesIDs, *booking.SeriesID)
+		}
+		included[*booking.SeriesID][booking.ID] = true
+	}
+
+	for _, seriesID := range seriesIDs {
+		var series models.BookingSeries
+		if err := database.DB.Preload("Room").Preload("Member").Preload("Users").First(&series, seriesID).Error; err != nil {
+			return nil, err
+		}
+		var occurrences []models.Booking
+		if err := database.DB.Preload("Room").Preload("Member").Preload("BookingUsers").
+			Where("series_id = ?", seriesID).Order("date asc, id asc").Find(&occurrences).Error; err != nil {
+			return nil, err
+		}
+		events = append(events, seriesEvents(&series, occurrences, included[seriesID], viewer)...)
+	}
+	return events, nil
+}
+
+// 将周期预定转换为一个带 RRULE 的周期事件：没有预定或被取消的发生用 EXDATE 排除，
+// 修改过的发生用 RECURRENCE-ID 覆盖，改到其他日期的预定作为单独的事件；included 为订阅中包含的预定
+func seriesEvents(series *models.BookingSeries, occurrences []models.Booking, included map[uint]bool, viewer *models.Member) []ics.Event {
+	var dates []string
+	if series.MaterializedUntil != "" {
+		dates = series.Occurrences(series.StartDate, series.MaterializedUntil)
+	}
+	byDate := make(map[string]*models.Booking)
+	for i := range occurrences {
+		if _, ok := byDate[occurrences[i].Date]; !ok {
+			byDate[occurrences[i].Date] = &occurrences[i]
+		}
+	}
+
+	covered := 0
+	for _, date := range dates {
+		if booking, ok := byDate[date]; ok && matchesSeries(series, booking) {
+			covered++
+		}
+	}
+
+	var events []ics.Event
+	used := make(map[uint]bool)
+	if covered > 0 {
+		uid := fmt.Sprintf("series-%d@%s", series.ID, uidDomain)
+		var attendees []string
+		for _, user := range series.Users {
+			attendees = append(attendees, user.Nickname)
+		}
+		master := ics.Event{
+			UID:          uid,
+			Summary:      series.Reason,
+			Description:  eventDescription(series.Member.Name, strings.Join(attendees, "、"), ""),
+			Location:     roomLocation(&series.Room),
+			Start:        bookingClock(dates[0], series.StartTime),
+			End:          bookingClock(dates[0], series.EndTime),
+			Status:       ics.StatusConfirmed,
+			RRule:        seriesRRule(series, dates),
+			Created:      series.CreatedAt,
+			LastModified: series.UpdatedAt,
+		}
+		var overrides []ics.Event
+		for _, date := range dates {
+			start := bookingClock(date, series.StartTime)
+			booking, ok := byDate[date]
+			if !ok {
+				master.ExDates = append(master.ExDates, start)
+				continue
+			}
+			used[booking.ID] = true
+			if matchesSeries(series, booking) {
+				continue
+			}
+			if !included[booking.ID] {
+				master.ExDates = append(master.ExDates, start)
+				continue
+			}
+			override := bookingEvent(booking, viewer)
+			override.UID = uid
+			override.RecurrenceID = &start
+			overrides = append(overrides, override)
+		}
+		events = append(events, master)
+		events = append(events, overrides...)
+	}
+
+	for i := range occurrences {
+		booking := &occurrences[i]
+		if !used[booking.ID] && included[booking.ID] {
+			events = append(events, bookingEvent(booking, viewer))
+		}
+	}
+	return events
+}
+
+// 周期事件的 RRULE：已结束的周期预定截止到最后一次发生，设置了截止日期的截止到该日期，否则按次数或不限
+func seriesRRule(series *models.BookingSeries, dates []string) string {
+	until := ""
+	if series.IsFinished(series.MaterializedUntil) {
+		until = dates[len(dates)-1]
+	} else if series.Until != "" {
+		until = series.Until
+	}
+	if until == "" {
+		return series.RRule()
+	}
+	// RRULE 中 COUNT 和 UNTIL 不能同时出现
+	bounded := *series
+	bounded.Count = 0
+	return bounded.RRule() + ";UNTIL=" + ics.FormatTime(bookingClock(until, series.StartTime))
+}
+
+// 预定与周期规则一致时由周期事件表示，否则需要单独覆盖
+func matchesSeries(series *models.BookingSeries, booking *models.Booking) bool {
+	if booking.Status != "active" && booking.Status != "expired" {
+		return false
+	}
+	if booking.IsPrivate || booking.RoomID != series.RoomID || booking.Reason != series.Reason ||
+		booking.StartTime != series.StartTime || booking.EndTime != series.EndTime {
+		return false
+	}
+	var attendees []string
+	for _, user := range series.Users {
+		attendees = append(attendees, user.Nickname)
+	}
+	return joinNicknames(booking.BookingUsers) == strings.Join(attendees, "、")
+}
+
+// 将单个预定转换为日历事件，viewer 无权查看私密会议详情时隐藏预定理由、参会人员和取消理由
+func bookingEvent(booking *models.Booking, viewer *models.Member) ics.Event {
+	visible := *booking
+	redacted := redactBooking(viewer, &visible)
+	booking = &visible
+	event := ics.Event{
+		UID:          fmt.Sprintf("booking-%d@%s", booking.ID, uidDomain),
+		Summary:      booking.Reason,
+		Location:     roomLocation(&booking.Room),
+		Start:        bookingClock(booking.Date, booking.StartTime),
+		End:          bookingClock(booking.Date, booking.EndTime),
+		Status:       eventStatus(booking.Status),
+		Created:      booking.CreatedAt,
+		LastModified: booking.UpdatedAt,
+	}
+	if redacted {
+		event.Summary = "私密会议"
+	}
+	attendees := joinNicknames(booking.BookingUsers)
+	cancelReason := ""
+	if event.Status == ics.StatusCancelled {
+		cancelReason = booking.CancelReason
+		if cancelReason == "" {
+			cancelReason = booking.ReviewReason
+		}
+	}
+	event.Description = eventDescription(booking.Member.Name, attendees, cancelReason)
+	return event
+}
+
+// 预定状态对应的日历事件状态：待审批为暂定，已取消、被拒绝和未签到释放的为已取消
+func eventStatus(status string) string {
+	switch status {
+	case "pending":
+		return ics.StatusTentative
+	case "cancelled", "rejected", "no_show":
+		return ics.StatusCancelled
+	}
+	return ics.StatusConfirmed
+}
+
+// 日历事件的描述，空项省略
+func eventDescription(organizer, attendees, cancelReason string) string {
+	var lines []string
+	if organizer != "" {
+		lines = append(lines, "预定人："+organizer)
+	}
+	if attendees != "" {
+		lines = append(lines, "参会人员："+attendees)
+	}
+	if cancelReason != "" {
+		lines = append(lines, "取消理由："+cancelReason)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// 日历事件的地点：会议室名称和楼层
+func roomLocation(room *models.Room) string {
+	if room.Floor == "" {
+		return room.Name
+	}
+	return room.Name + " " + room.Floor
+}
+
+// 生成订阅地址中的随机 token
+func newFeedToken() string {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// 订阅地址的路径
+func feedPath(token string) string {
+	return "/feeds/" + token + "/calendar.ics"
+}
+
+// 对外访问的地址，通过环境变量 PUBLIC_URL 配置，如 https://roomly.example.com
+func publicURL() string {
+	return strings.TrimRight(os.Getenv("PUBLIC_URL"), "/")
+}
+
+// 生成订阅的 http(s) 和 webcal 地址，未配置 PUBLIC_URL 时使用请求的地址
+func newCalendarFeedResponse(c *gin.Context, feed models.CalendarFeed) calendarFeedResponse {
+	base := publicURL()
+	if base == "" {
+		scheme := "http"
+		if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		base = scheme + "://" + c.Request.Host
+	}
+	url := base + feedPath(feed.Token)
+	webcal := "webcal://" + strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://")
+	return calendarFeedResponse{CalendarFeed: feed, URL: url, WebcalURL: webcal}
+}
+
+// 按开始时间排序日历事件，同一时间的按 UID 排序，保证订阅内容稳定
+func sortEvents(events []ics.Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].Start.Equal(events[j].Start) {
+			return events[i].Start.Before(events[j].Start)
+		}
+		return events[i].UID < events[j].UID
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"roomly/database"
+	"roomly/middleware"
 	"roomly/models"
 
 	"github.com/gin-gonic/gin"
@@ -64,13 +65,18 @@ func GetMembers(c *gin.Context) {
 func GetMember(c *gin.Context) {
 	id := c.Param("id")
 	var member models.Member
-	if err := database.DB.First(&member, id).Error; err != nil {
+	if err := database.DB.Preload("ManagedRooms").First(&member, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
 		return
 	}
 	c.JSON(http.StatusOK, member)
 }
 
+// 获取当前登录会员
+func GetCurrentMember(c *gin.Context) {
+	c.JSON(http.StatusOK, middleware.CurrentMember(c))
+}
+
 // 根据dootask_id获取会员
 func GetMemberForDootaskId(c *gin.Context) {
 	id := c.Param("id")
@@ -90,7 +96,7 @@ func CreateMember(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Create(&member).Error; err != nil {
+	if err := database.DB.Omit("ManagedRooms").Create(&member).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create member"})
 		return
 	}
@@ -113,7 +119,7 @@ func UpdateMember(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Save(&member).Error; err != nil {
+	if err := database.DB.Omit("ManagedRooms").Save(&member).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member"})
 		return
 	}
@@ -125,7 +131,19 @@ func UpdateMember(c *gin.Context) {
 func DeleteMember(c *gin.Context) {
 	id := c.Param("id")
 
-	if err := database.DB.Delete(&models.Member{}, id).Error; err != nil {
+	var member models.Member
+	if err := database.DB.First(&member, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	// 清理会议室管理员分配
+	if err := database.DB.Model(&member).Association("ManagedRooms").Clear(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove room admin assignments"})
+		return
+	}
+
+	if err := database.DB.Delete(&member).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete member"})
 		return
 	}
@@ -162,11 +180,14 @@ func SetAdminPermission(c *gin.Context) {
 }
 
 // 设置会议室管理员权限
+// 传入 room_ids 时将会员设为这些会议室的管理员；
+// 否则兼容旧版本：is_room_admin 为 true 时分配所有会议室，为 false 时移除全部分配
 func SetRoomAdminPermission(c *gin.Context) {
 	id := c.Param("id")
 
 	var request struct {
-		IsRoomAdmin bool `json:"is_room_admin"`
+		IsRoomAdmin bool   `json:"is_room_admin"`
+		RoomIDs     []uint `json:"room_ids"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -180,11 +201,24 @@ func SetRoomAdminPermission(c *gin.Context) {
 		return
 	}
 
-	member.IsRoomAdmin = request.IsRoomAdmin
-	if err := database.DB.Save(&member).Error; err != nil {
+	var rooms []models.Room
+	if request.RoomIDs != nil {
+		if len(request.RoomIDs) > 0 {
+			database.DB.Where("id IN ?", request.RoomIDs).Find(&rooms)
+		}
+	} else if request.IsRoomAdmin {
+		database.DB.Find(&rooms)
+	}
+
+	if err := database.DB.Model(&member).Association("ManagedRooms").Replace(rooms); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update room admin permission"})
+		return
+	}
+	if err := syncRoomAdminFlag(member.ID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update room admin permission"})
 		return
 	}
 
+	database.DB.Preload("ManagedRooms").First(&member, member.ID)
 	c.JSON(http.StatusOK, member)
 }
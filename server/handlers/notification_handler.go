@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"roomly/database"
+	"roomly/models"
+	"roomly/outbox"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 获取发件箱中的通知，可按状态（pending、sent、dead）、消息类型和接收人筛选
+func GetNotifications(c *gin.Context) {
+	// 解析分页参数
+	pageStr := c.DefaultQuery("page", "1")
+	pageSizeStr := c.DefaultQuery("page_size", "20")
+	page, err := strconv.Atoi(pageStr)
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(pageSizeStr)
+	if err != nil || pageSize < 1 {
+		pageSize = 20
+	}
+
+	db := database.DB.Model(&models.Notification{})
+	if status := c.Query("status"); status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if msgType := c.Query("msg_type"); msgType != "" {
+		db = db.Where("msg_type = ?", msgType)
+	}
+	if userid := c.Query("userid"); userid != "" {
+		db = db.Where("userid = ?", userid)
+	}
+
+	var total int64
+	db.Count(&total)
+
+	var notifications []models.Notification
+	if err := db.Order("id desc").Limit(pageSize).Offset((page - 1) * pageSize).Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":  notifications,
+		"total": total,
+	})
+}
+
+// 重新发送单条未发送的通知
+func RetryNotification(c *gin.Context) {
+	var notification models.Notification
+	if err := database.DB.First(&notification, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+	if err := outbox.Retry(database.DB, &notification); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	go outbox.Deliver()
+	c.JSON(http.StatusOK, notification)
+}
+
+// 重新发送所有投递失败（dead）的通知
+func RetryDeadNotifications(c *gin.Context) {
+	var dead []models.Notification
+	if err := database.DB.Where("status = ?", outbox.StatusDead).Find(&dead).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+	for i := range dead {
+		if err := outbox.Retry(database.DB, &dead[i]); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry notifications"})
+			return
+		}
+	}
+
+	go outbox.Deliver()
+	c.JSON(http.StatusOK, gin.H{"retried": len(dead)})
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"roomly/database"
+	"roomly/models"
+	"roomly/policy"
+	"roomly/quota"
+	"roomly/timeslot"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 计入每周使用时长的预定状态，已取消和被拒绝的预定不计入
+var weeklyUsageStatuses = []string{"pending", "active", "expired", "no_show"}
+
+// 获取所有配额策略
+func GetQuotaPolicies(c *gin.Context) {
+	var policies []models.QuotaPolicy
+	if err := database.DB.Preload("Room").Order("id asc").Find(&policies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quota policies"})
+		return
+	}
+	c.JSON(http.StatusOK, policies)
+}
+
+// 创建配额策略
+func CreateQuotaPolicy(c *gin.Context) {
+	var quotaPolicy models.QuotaPolicy
+	if err := c.ShouldBindJSON(&quotaPolicy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateQuotaPolicy(&quotaPolicy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	quotaPolicy.ID = 0
+	if err := database.DB.Omit("Room").Create(&quotaPolicy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create quota policy"})
+		return
+	}
+	database.DB.Preload("Room").First(&quotaPolicy, quotaPolicy.ID)
+	c.JSON(http.StatusCreated, quotaPolicy)
+}
+
+// 更新配额策略
+func UpdateQuotaPolicy(c *gin.Context) {
+	var quotaPolicy models.QuotaPolicy
+	if err := database.DB.First(&quotaPolicy, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quota policy not found"})
+		return
+	}
+
+	id := quotaPolicy.ID
+	if err := c.ShouldBindJSON(&quotaPolicy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	quotaPolicy.ID = id
+	if err := validateQuotaPolicy(&quotaPolicy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Omit("Room").Save(&quotaPolicy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update quota policy"})
+		return
+	}
+	database.DB.Preload("Room").First(&quotaPolicy, quotaPolicy.ID)
+	c.JSON(http.StatusOK, quotaPolicy)
+}
+
+// 删除配额策略
+func DeleteQuotaPolicy(c *gin.Context) {
+	result := database.DB.Delete(&models.QuotaPolicy{}, c.Param("id"))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete quota policy"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quota policy not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Quota policy deleted successfully"})
+}
+
+// 校验配额策略，适用对象默认为普通会员
+func validateQuotaPolicy(quotaPolicy *models.QuotaPolicy) error {
+	if quotaPolicy.Name == "" {
+		return errors.New("name is required")
+	}
+	if quotaPolicy.AppliesTo == "" {
+		quotaPolicy.AppliesTo = quota.AudienceMember
+	}
+	if !quota.IsValidAudience(quotaPolicy.AppliesTo) {
+		return errors.New("applies_to must be member or admin")
+	}
+	if quotaPolicy.RoomID != nil {
+		var room models.Room
+		if err := database.DB.First(&room, *quotaPolicy.RoomID).Error; err != nil {
+			return errors.New("Room not found")
+		}
+	}
+	return quotaPolicy.Limits().Validate()
+}
+
+// 检查预定是否超出会员适用的配额策略，返回违反的策略和规则；
+// excludeID 为修改中的预定，不计入已有使用量
+func checkQuota(db *gorm.DB, room *models.Room, member *models.Member, date string, window timeslot.Window, excludeID uint) (*models.QuotaPolicy, *quota.Violation) {
+	audience := quota.AudienceMember
+	if policy.CanManageRoom(member, room.ID) == nil {
+		audience = quota.AudienceAdmin
+	}
+
+	var policies []models.QuotaPolicy
+	db.Where("applies_to = ? AND (room_id IS NULL OR room_id = ?)", audience, room.ID).Order("id asc").Find(&policies)
+	if len(policies) == 0 {
+		return nil, nil
+	}
+
+	request := quota.Request{
+		Duration: window.Duration(),
+		Lead:     time.Until(bookingClock(date, window.StartClock())),
+	}
+	for i := range policies {
+		usage := quotaUsage(db, &policies[i], member.ID, room.ID, date, excludeID)
+		if violation := quota.Check(policies[i].Limits(), request, usage); violation != nil {
+			return &policies[i], violation
+		}
+	}
+	return nil, nil
+}
+
+// quotaExceededError 预定超出配额，在写入预定的事务中返回以回滚整个事务
+type quotaExceededError struct {
+	policy    *models.QuotaPolicy
+	violation *quota.Violation
+}
+
+func (e *quotaExceededError) Error() string {
+	return e.violation.Error()
+}
+
+// 在写入预定的事务中检查配额；写事务串行执行，并发的预定请求不会同时通过检查
+func enforceQuota(tx *gorm.DB, room *models.Room, member *models.Member, date string, window timeslot.Window, excludeID uint) error {
+	if quotaPolicy, violation := checkQuota(tx, room, member, date, window, excludeID); violation != nil {
+		return &quotaExceededError{policy: quotaPolicy, violation: violation}
+	}
+	return nil
+}
+
+// 统计会员在配额策略范围内的已有使用量
+func quotaUsage(db *gorm.DB, quotaPolicy *models.QuotaPolicy, memberID uint, roomID uint, date string, excludeID uint) quota.Usage {
+	var usage quota.Usage
+
+	// 未结束的预定数，全局策略统计所有会议室
+	if quotaPolicy.MaxActiveBookings > 0 {
+		now := time.Now()
+		today := now.Format("2006-01-02")
+		query := db.Model(&models.Booking{}).
+			Where("member_id = ? AND status IN ? AND id <> ?", memberID, models.HoldingStatuses, excludeID).
+			Where("(date > ? OR (date = ? AND end_time > ?))", today, today, now.Format("15:04"))
+		if quotaPolicy.RoomID != nil {
+			query = query.Where("room_id = ?", *quotaPolicy.RoomID)
+		}
+		var count int64
+		query.Count(&count)
+		usage.ActiveBookings = int(count)
+	}
+
+	// 同一会议室同一周已预定的时长
+	if quotaPolicy.MaxWeeklyMinutes > 0 {
+		day, _ := time.Parse("2006-01-02", date)
+		monday, sunday := quota.WeekRange(day)
+		var bookings []models.Booking
+		db.Where("member_id = ? AND room_id = ? AND status IN ? AND id <> ? AND date BETWEEN ? AND ?",
+			memberID, roomID, weeklyUsageStatuses, excludeID, monday.Format("2006-01-02"), sunday.Format("2006-01-02")).
+			Find(&bookings)
+		for _, booking := range bookings {
+			usage.WeeklyMinutes += timeslot.MustWindow(booking.StartTime, booking.EndTime).Duration()
+		}
+	}
+	return usage
+}
+
+// 配额超限的错误响应，包含违反的策略和规则
+func quotaError(quotaPolicy *models.QuotaPolicy, violation *quota.Violation) gin.H {
+	return gin.H{
+		"error": violation.Error(),
+		"quota": gin.H{
+			"policy_id":   quotaPolicy.ID,
+			"policy_name": quotaPolicy.Name,
+			"rule":        violation.Rule,
+			"limit":       violation.Limit,
+			"actual":      violation.Actual,
+		},
+	}
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"roomly/database"
+	"roomly/message"
+	"roomly/middleware"
+	"roomly/models"
+	"roomly/notify"
+	"roomly/outbox"
+	"roomly/policy"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 没有设置通知偏好时，会议开始前多少分钟提醒
+const defaultReminderMinutes = 15
+
+// 会前提醒最多提前的分钟数
+const maxReminderMinutes = 24 * 60
+
+// 获取当前会员的通知偏好，未设置时返回默认值
+func GetMyPreferences(c *gin.Context) {
+	c.JSON(http.StatusOK, memberPreference(middleware.CurrentMember(c).ID))
+}
+
+// 更新当前会员的通知偏好，只更新请求中提供的字段
+func UpdateMyPreferences(c *gin.Context) {
+	var request struct {
+		ReminderMinutes *int      `json:"reminder_minutes"`
+		Channels        *[]string `json:"channels"`
+		Email           *string   `json:"email"`
+		WebhookURL      *string   `json:"webhook_url"`
+		Locale          *string   `json:"locale"`
+		DailyDigest     *bool     `json:"daily_digest"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member := middleware.CurrentMember(c)
+	preference := memberPreference(member.ID)
+	if request.ReminderMinutes != nil {
+		preference.ReminderMinutes = *request.ReminderMinutes
+	}
+	if request.Channels != nil {
+		preference.Channels = strings.Join(*request.Channels, ",")
+	}
+	if request.Email != nil {
+		preference.Email = strings.TrimSpace(*request.Email)
+	}
+	if request.WebhookURL != nil {
+		preference.WebhookURL = strings.TrimSpace(*request.WebhookURL)
+	}
+	if request.Locale != nil {
+		preference.Locale = *request.Locale
+	}
+	if request.DailyDigest != nil {
+		if *request.DailyDigest && policy.RequireRole(member, policy.RoleRoomAdmin) != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only room admins can subscribe to the daily digest"})
+			return
+		}
+		preference.DailyDigest = *request.DailyDigest
+	}
+	if err := validatePreference(&preference); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Save(&preference).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update preferences"})
+		return
+	}
+	c.JSON(http.StatusOK, preference)
+}
+
+// 获取可以选择的通知渠道
+func GetNotificationChannels(c *gin.Context) {
+	c.JSON(http.StatusOK, notify.Channels())
+}
+
+// 校验通知偏好：提醒时间范围、渠道可用、语言受支持，选择 email 或 webhook 渠道时需要填写对应地址，
+// 回调地址不能指向本机或内网
+func validatePreference(preference *models.MemberPreference) error {
+	if preference.ReminderMinutes < 0 || preference.ReminderMinutes > maxReminderMinutes {
+		return errors.New("reminder_minutes must be between 0 and 1440")
+	}
+	if !message.IsLocale(preference.Locale) {
+		return errors.New("locale must be one of " + strings.Join(message.Locales, ", "))
+	}
+	channels, err := notify.ParseChannels(preference.Channels)
+	if err != nil {
+		return err
+	}
+	if len(channels) == 0 {
+		return errors.New("At least one channel is required")
+	}
+	preference.Channels = strings.Join(channels, ",")
+
+	if preference.Email != "" {
+		if _, err := mail.ParseAddress(preference.Email); err != nil {
+			return errors.New("Invalid email address")
+		}
+	}
+	if preference.WebhookURL != "" {
+		if err := notify.CheckWebhookURL(preference.WebhookURL); err != nil {
+			return err
+		}
+	}
+	for _, channel := range channels {
+		if channel == notify.ChannelEmail && preference.Email == "" {
+			return errors.New("email is required for the email channel")
+		}
+		if channel == notify.ChannelWebhook && preference.WebhookURL == "" {
+			return errors.New("webhook_url is required for the webhook channel")
+		}
+	}
+	return nil
+}
+
+// 加载会员的通知偏好，没有记录时返回默认设置（未保存）
+func memberPreference(memberID uint) models.MemberPreference {
+	preference := models.MemberPreference{MemberID: memberID, ReminderMinutes: defaultReminderMinutes, Channels: notify.ChannelDooTask, Locale: message.DefaultLocale}
+	database.DB.Where("member_id = ?", memberID).First(&preference)
+	return preference
+}
+
+// 定时任务：给即将开始的会议的参会人员和预定人发送会前提醒，提前时间按各自的通知偏好；
+// 提醒记录和通知在同一事务中写入，同一预定同一开始时间每人只提醒一次，重启后也不会重复发送
+func SendBookingReminders() {
+	// 未配置 DOOTASK_BOT_TOKEN 时无法发送提醒
+	token := models.SystemToken()
+	if token == "" {
+		return
+	}
+
+	now := time.Now()
+	horizon := now.Add(maxReminderMinutes * time.Minute)
+	var bookings []models.Booking
+	database.DB.Preload("Room").Preload("Member").Preload("BookingUsers").
+		Where("status = ? AND date BETWEEN ? AND ?", "active", now.Format("2006-01-02"), horizon.Format("2006-01-02")).
+		Find(&bookings)
+	if len(bookings) == 0 {
+		return
+	}
+
+	var userids []uint
+	for i := range bookings {
+		userids = append(userids, reminderRecipients(&bookings[i])...)
+	}
+	leads := reminderLeads(userids)
+
+	for i := range bookings {
+		booking := &bookings[i]
+		start := bookingClock(booking.Date, booking.StartTime)
+		if !now.Before(start) || start.After(horizon) {
+			continue
+		}
+
+		startsAt := booking.Date + " " + booking.StartTime
+		minutes := int(start.Sub(now).Round(time.Minute).Minutes())
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			var due []int
+			for _, userid := range reminderRecipients(booking) {
+				lead, ok := leads[userid]
+				if !ok {
+					lead = defaultReminderMinutes
+				}
+				if lead == 0 || now.Before(start.Add(-time.Duration(lead)*time.Minute)) {
+					continue
+				}
+				reminder := models.BookingReminder{BookingID: booking.ID, Userid: userid, StartsAt: startsAt, LeadMinutes: lead, SentAt: now}
+				result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&reminder)
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected == 0 {
+					continue
+				}
+				due = append(due, int(userid))
+			}
+			return outbox.Publish(tx, notify.Event{
+				Type:      "reminder",
+				UserIDs:   due,
+				Token:     token,
+				Date:      booking.Date,
+				StartTime: booking.StartTime,
+				EndTime:   booking.EndTime,
+				RoomName:  booking.Room.Name,
+				Reason:    booking.Reason,
+				Attendees: joinNicknames(booking.BookingUsers),
+				Content:   []string{strconv.Itoa(minutes)},
+			})
+		})
+		if err != nil {
+			fmt.Printf("记录会前提醒失败: 预定%d: %v\n", booking.ID, err)
+		}
+	}
+}
+
+// 会前提醒的接收人：预定人和所有参会人员
+func reminderRecipients(booking *models.Booking) []uint {
+	seen := make(map[uint]bool)
+	var userids []uint
+	add := func(userid uint) {
+		if userid == 0 || seen[userid] {
+			return
+		}
+		seen[userid] = true
+		userids = append(userids, userid)
+	}
+	add(booking.Member.DootaskID)
+	for _, user := range booking.BookingUsers {
+		add(user.Userid)
+	}
+	return userids
+}
+
+// 按 dootask 用户 ID 加载会员设置的提醒时间，不是会员或未设置的用户不在结果中
+func reminderLeads(userids []uint) map[uint]int {
+	leads := make(map[uint]int)
+	if len(userids) == 0 {
+		return leads
+	}
+	var rows []struct {
+		DootaskID       uint
+		ReminderMinutes int
+	}
+	database.DB.Table("member_preferences").
+		Select("members.dootask_id, member_preferences.reminder_minutes").
+		Joins("JOIN members ON members.id = member_preferences.member_id").
+		Where("members.dootask_id IN ?", userids).
+		Scan(&rows)
+	for _, row := range rows {
+		leads[row.DootaskID] = row.ReminderMinutes
+	}
+	return leads
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+
+	"roomly/database"
+	"roomly/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 获取会议室管理员列表
+func GetRoomAdmins(c *gin.Context) {
+	id := c.Param("id")
+	var room models.Room
+	if err := database.DB.Preload("Admins").First(&room, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		return
+	}
+	c.JSON(http.StatusOK, room.Admins)
+}
+
+// 添加会议室管理员
+func AddRoomAdmin(c *gin.Context) {
+	id := c.Param("id")
+
+	var request struct {
+		MemberID uint `json:"member_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var room models.Room
+	if err := database.DB.First(&room, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		return
+	}
+	var member models.Member
+	if err := database.DB.First(&member, request.MemberID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	if err := database.DB.Model(&room).Association("Admins").Append(&member); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add room admin"})
+		return
+	}
+	if err := syncRoomAdminFlag(member.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update room admin permission"})
+		return
+	}
+
+	database.DB.Preload("Admins").First(&room, room.ID)
+	c.JSON(http.StatusOK, room.Admins)
+}
+
+// 移除会议室管理员
+func RemoveRoomAdmin(c *gin.Context) {
+	id := c.Param("id")
+	memberID := c.Param("member_id")
+
+	var room models.Room
+	if err := database.DB.First(&room, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		return
+	}
+	var member models.Member
+	if err := database.DB.First(&member, memberID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	if err := database.DB.Model(&room).Association("Admins").Delete(&member); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove room admin"})
+		return
+	}
+	if err := syncRoomAdminFlag(member.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update room admin permission"})
+		return
+	}
+
+	database.DB.Preload("Admins").First(&room, room.ID)
+	c.JSON(http.StatusOK, room.Admins)
+}
+
+// 根据会员管理的会议室数量同步 IsRoomAdmin 标记
+func syncRoomAdminFlag(memberID uint) error {
+	member := models.Member{ID: memberID}
+	count := database.DB.Model(&member).Association("ManagedRooms").Count()
+	return database.DB.Model(&member).Update("is_room_admin", count > 0).Error
+}
+
+// 查找指定会议室的管理员 dootask_id
+func getRoomAdminIDs(roomID uint) []int {
+	var adminIDs []int
+	var room models.Room
+	if err := database.DB.Preload("Admins").First(&room, roomID).Error; err != nil {
+		return adminIDs
+	}
+	for _, admin := range room.Admins {
+		adminIDs = append(adminIDs, int(admin.DootaskID))
+	}
+	return adminIDs
+}
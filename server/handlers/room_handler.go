@@ -1,13 +1,20 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"roomly/database"
+	"roomly/middleware"
 	"roomly/models"
+	"roomly/policy"
+	"roomly/timeslot"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // 获取所有会议室
@@ -25,12 +32,12 @@ func GetRooms(c *gin.Context) {
 	}
 
 	var total int64
-	db := database.DB.Model(&models.Room{})
+	db := filterRooms(c, database.DB.Model(&models.Room{}))
 	db.Count(&total)
 
 	var rooms []models.Room
 	db = db.Order("id desc").Limit(pageSize).Offset((page - 1) * pageSize)
-	if err := db.Find(&rooms).Error; err != nil {
+	if err := preloadRoomDetails(db).Find(&rooms).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rooms"})
 		return
 	}
@@ -43,7 +50,7 @@ func GetRooms(c *gin.Context) {
 // 获取开放的会议室
 func GetOpenRooms(c *gin.Context) {
 	var rooms []models.Room
-	if err := database.DB.Where("is_open = ?", true).Find(&rooms).Error; err != nil {
+	if err := preloadRoomDetails(filterRooms(c, database.DB)).Where("is_open = ?", true).Find(&rooms).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch open rooms"})
 		return
 	}
@@ -54,13 +61,64 @@ func GetOpenRooms(c *gin.Context) {
 func GetRoom(c *gin.Context) {
 	id := c.Param("id")
 	var room models.Room
-	if err := database.DB.First(&room, id).Error; err != nil {
+	if err := preloadRoomDetails(database.DB).First(&room, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
 		return
 	}
 	c.JSON(http.StatusOK, room)
 }
 
+// 按位置、设施和容量筛选会议室：building、floor、area、amenities（逗号分隔的设施 code，需全部具备）、min_capacity
+func filterRooms(c *gin.Context, db *gorm.DB) *gorm.DB {
+	if building := c.Query("building"); building != "" {
+		db = db.Where("building = ?", building)
+	}
+	if floor := c.Query("floor"); floor != "" {
+		db = db.Where("floor = ?", floor)
+	}
+	if area := c.Query("area"); area != "" {
+		db = db.Where("area = ?", area)
+	}
+	if minCapacity, err := strconv.Atoi(c.Query("min_capacity")); err == nil && minCapacity > 0 {
+		db = db.Where("capacity >= ?", minCapacity)
+	}
+	if codes := splitCodes(c.Query("amenities")); len(codes) > 0 {
+		db = db.Where("id IN (?)", roomsWithAmenities(codes))
+	}
+	return db
+}
+
+// 具备所有指定设施的会议室 ID 子查询
+func roomsWithAmenities(codes []string) *gorm.DB {
+	return database.DB.Table("room_amenities").
+		Select("room_amenities.room_id").
+		Joins("JOIN amenities ON amenities.id = room_amenities.amenity_id").
+		Where("amenities.code IN ?", codes).
+		Group("room_amenities.room_id").
+		Having("COUNT(DISTINCT amenities.code) = ?", len(codes))
+}
+
+// 拆分逗号分隔的 code 列表，忽略空白项和重复项
+func splitCodes(value string) []string {
+	var codes []string
+	seen := make(map[string]bool)
+	for _, code := range strings.Split(value, ",") {
+		code = strings.TrimSpace(code)
+		if code != "" && !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// 加载会议室的营业时间、设施和照片
+func preloadRoomDetails(db *gorm.DB) *gorm.DB {
+	return db.Preload("BusinessHours").Preload("Amenities").Preload("Photos", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order asc, id asc")
+	})
+}
+
 // 创建会议室
 func CreateRoom(c *gin.Context) {
 	var room models.Room
@@ -69,7 +127,17 @@ func CreateRoom(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Create(&room).Error; err != nil {
+	if err := validateRoomRules(&room); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateBusinessHours(room.BusinessHours); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 营业时间随会议室一起创建，设施和照片通过单独的接口维护
+	if err := database.DB.Omit("Admins", "Amenities", "Photos").Create(&room).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create room"})
 		return
 	}
@@ -92,7 +160,13 @@ func UpdateRoom(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Save(&room).Error; err != nil {
+	if err := validateRoomRules(&room); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 营业时间、设施和照片通过单独的接口维护
+	if err := database.DB.Omit("Admins", "BusinessHours", "Amenities", "Photos").Save(&room).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update room"})
 		return
 	}
@@ -106,7 +180,7 @@ func DeleteRoom(c *gin.Context) {
 
 	// 检查是否有预定记录
 	var bookingCount int64
-	if err := database.DB.Model(&models.Booking{}).Where("room_id = ? AND status = ?", id, "active").Count(&bookingCount).Error; err != nil {
+	if err := database.DB.Model(&models.Booking{}).Where("room_id = ? AND status IN ?", id, models.HoldingStatuses).Count(&bookingCount).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check bookings"})
 		return
 	}
@@ -116,7 +190,44 @@ func DeleteRoom(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Delete(&models.Room{}, id).Error; err != nil {
+	var room models.Room
+	if err := database.DB.First(&room, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		return
+	}
+
+	// 清理营业时间和关闭时间段
+	if err := database.DB.Where("room_id = ?", room.ID).Delete(&models.RoomBusinessHour{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove business hours"})
+		return
+	}
+	if err := database.DB.Where("room_id = ?", room.ID).Delete(&models.RoomBlackout{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove room blackouts"})
+		return
+	}
+
+	// 清理设施和照片
+	if err := database.DB.Model(&room).Association("Amenities").Clear(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove room amenities"})
+		return
+	}
+	if err := database.DB.Where("room_id = ?", room.ID).Delete(&models.RoomPhoto{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove room photos"})
+		return
+	}
+
+	// 清理会议室管理员分配
+	var admins []models.Member
+	database.DB.Model(&room).Association("Admins").Find(&admins)
+	if err := database.DB.Model(&room).Association("Admins").Clear(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove room admins"})
+		return
+	}
+	for _, admin := range admins {
+		syncRoomAdminFlag(admin.ID)
+	}
+
+	if err := database.DB.Delete(&room).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete room"})
 		return
 	}
@@ -134,6 +245,12 @@ func ToggleRoomStatus(c *gin.Context) {
 		return
 	}
 
+	// 仅管理员和该会议室的管理员可以操作
+	if err := policy.CanManageRoom(middleware.CurrentMember(c), room.ID); err != nil {
+		middleware.AbortWithPolicyError(c, err)
+		return
+	}
+
 	room.IsOpen = !room.IsOpen
 	if err := database.DB.Save(&room).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to toggle room status"})
@@ -142,3 +259,113 @@ func ToggleRoomStatus(c *gin.Context) {
 
 	c.JSON(http.StatusOK, room)
 }
+
+// 设置会议室营业时间，整体替换；传入空列表表示全天开放
+func UpdateRoomBusinessHours(c *gin.Context) {
+	id := c.Param("id")
+
+	var room models.Room
+	if err := database.DB.First(&room, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		return
+	}
+
+	// 仅管理员和该会议室的管理员可以操作
+	if err := policy.CanManageRoom(middleware.CurrentMember(c), room.ID); err != nil {
+		middleware.AbortWithPolicyError(c, err)
+		return
+	}
+
+	var request struct {
+		BusinessHours []models.RoomBusinessHour `json:"business_hours"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if err := validateBusinessHours(request.BusinessHours); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("room_id = ?", room.ID).Delete(&models.RoomBusinessHour{}).Error; err != nil {
+			return err
+		}
+		for _, hours := range request.BusinessHours {
+			hours.ID = 0
+			hours.RoomID = room.ID
+			if err := tx.Create(&hours).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update business hours"})
+		return
+	}
+
+	database.DB.Preload("BusinessHours").First(&room, room.ID)
+	c.JSON(http.StatusOK, room)
+}
+
+// 校验会议室的时间段长度、预定时长限制、签到宽限时间、非工作日策略和超员策略，未设置时使用默认值
+func validateRoomRules(room *models.Room) error {
+	if room.SlotMinutes == 0 {
+		room.SlotMinutes = timeslot.DefaultLength
+	}
+	if !timeslot.IsSupportedLength(room.SlotMinutes) {
+		return errors.New("slot_minutes must be one of 15, 30, 60")
+	}
+	if room.MinDuration < 0 || room.MaxDuration < 0 {
+		return errors.New("min_duration and max_duration must not be negative")
+	}
+	if room.CheckInGrace < 0 {
+		return errors.New("check_in_grace must not be negative")
+	}
+	if room.MaxDuration > 0 && room.MinDuration > room.MaxDuration {
+		return errors.New("min_duration must not exceed max_duration")
+	}
+	switch room.HolidayPolicy {
+	case "":
+		room.HolidayPolicy = models.HolidayWarn
+	case models.HolidayAllow, models.HolidayWarn, models.HolidayReject:
+	default:
+		return errors.New("holiday_policy must be one of allow, warn, reject")
+	}
+	switch room.CapacityPolicy {
+	case "":
+		room.CapacityPolicy = models.CapacityWarn
+	case models.CapacityWarn, models.CapacityReject, models.CapacityOverride:
+	default:
+		return errors.New("capacity_policy must be one of warn, reject, override")
+	}
+	return nil
+}
+
+// 校验营业时间：星期取值 0-6（0 为周日），每天最多一条，开始时间早于结束时间
+func validateBusinessHours(hours []models.RoomBusinessHour) error {
+	seen := make(map[int]bool)
+	for _, h := range hours {
+		if h.Weekday < 0 || h.Weekday > 6 {
+			return errors.New("weekday must be between 0 and 6")
+		}
+		if seen[h.Weekday] {
+			return fmt.Errorf("Duplicate business hours for weekday %d", h.Weekday)
+		}
+		seen[h.Weekday] = true
+		open, err := timeslot.Parse(h.Open)
+		if err != nil {
+			return fmt.Errorf("Invalid open time: %s", h.Open)
+		}
+		closing, err := timeslot.Parse(h.Close)
+		if err != nil {
+			return fmt.Errorf("Invalid close time: %s", h.Close)
+		}
+		if open >= closing {
+			return errors.New("open time must be before close time")
+		}
+	}
+	return nil
+}
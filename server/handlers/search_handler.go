@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"roomly/database"
+	"roomly/middleware"
+	"roomly/models"
+	"roomly/timeslot"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 一次搜索最多覆盖的天数
+const maxSearchDays = 14
+
+// 会议室的一段连续空闲时间
+type freeWindow struct {
+	Date     string `json:"date"`
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Duration int    `json:"duration"` // 分钟
+}
+
+// 会议室搜索结果
+type roomSearchResult struct {
+	Room        models.Room  `json:"room"`
+	CapacityFit int          `json:"capacity_fit"` // 容量超出所需人数的数量，越小越合适
+	Windows     []freeWindow `json:"windows"`
+}
+
+// 按条件搜索有空闲时间的会议室：date 或 start_date/end_date、duration（分钟）、
+// earliest/latest（HH:MM）、min_capacity、equipment（逗号分隔的设施 code）以及 building/floor/area；
+// 结果按容量匹配度排序
+func SearchRooms(c *gin.Context) {
+	startDate := c.Query("start_date")
+	endDate := c.Query("end_date")
+	if date := c.Query("date"); date != "" {
+		startDate, endDate = date, date
+	}
+	if startDate == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date or start_date is required"})
+		return
+	}
+	if endDate == "" {
+		endDate = startDate
+	}
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+		return
+	}
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+		return
+	}
+	if end.Before(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must not be before start_date"})
+		return
+	}
+	if end.Sub(start) >= maxSearchDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search range must not exceed 14 days"})
+		return
+	}
+
+	duration, err := strconv.Atoi(c.DefaultQuery("duration", "0"))
+	if err != nil || duration < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid duration"})
+		return
+	}
+	minCapacity, err := strconv.Atoi(c.DefaultQuery("min_capacity", "0"))
+	if err != nil || minCapacity < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_capacity"})
+		return
+	}
+	bounds, err := timeslot.ParseWindow(c.DefaultQuery("earliest", "00:00"), c.DefaultQuery("latest", "24:00"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid earliest or latest, use HH:MM"})
+		return
+	}
+
+	var dates []string
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		dates = append(dates, day.Format("2006-01-02"))
+	}
+
+	// 候选会议室，按位置、容量和设施筛选；equipment 与 amenities 相同
+	db := filterRooms(c, database.DB)
+	if codes := splitCodes(c.Query("equipment")); len(codes) > 0 {
+		db = db.Where("id IN (?)", roomsWithAmenities(codes))
+	}
+	var rooms []models.Room
+	if err := preloadRoomDetails(db).Where("is_open = ?", true).Find(&rooms).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rooms"})
+		return
+	}
+	if len(rooms) == 0 {
+		c.JSON(http.StatusOK, []roomSearchResult{})
+		return
+	}
+	var roomIDs []uint
+	for _, room := range rooms {
+		roomIDs = append(roomIDs, room.ID)
+	}
+
+	// 一次性加载所有候选会议室在搜索范围内的占用情况
+	occupancy, err := loadRoomOccupancy(roomIDs, startDate, endDate, middleware.CurrentMember(c).ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+		return
+	}
+
+	results := []roomSearchResult{}
+	for i := range rooms {
+		room := &rooms[i]
+
+		// 所需时长按会议室的时间段长度向上取整，并需满足会议室的时长限制
+		needed := duration
+		if length := room.SlotLength(); needed%length != 0 {
+			needed += length - needed%length
+		}
+		if needed == 0 {
+			needed = room.SlotLength()
+		}
+		if needed < room.MinDuration {
+			needed = room.MinDuration
+		}
+		if room.MaxDuration > 0 && needed > room.MaxDuration {
+			continue
+		}
+
+		var windows []freeWindow
+		for _, date := range dates {
+			windows = append(windows, freeWindows(occupancy.slots(room, date), date, bounds, needed)...)
+		}
+		if len(windows) == 0 {
+			continue
+		}
+
+		results = append(results, roomSearchResult{
+			Room:        *room,
+			CapacityFit: room.Capacity - minCapacity,
+			Windows:     windows,
+		})
+	}
+
+	// 容量最接近所需人数的会议室排在前面，其次是空闲时间更多的会议室
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].CapacityFit != results[j].CapacityFit {
+			return results[i].CapacityFit < results[j].CapacityFit
+		}
+		return totalFreeMinutes(results[i].Windows) > totalFreeMinutes(results[j].Windows)
+	})
+
+	c.JSON(http.StatusOK, results)
+}
+
+// 合并限定范围内连续的空闲时间段，返回不短于 needed 分钟的空闲时间；当天已开始的时间段不算空闲
+func freeWindows(slots []models.TimeSlot, date string, bounds timeslot.Window, needed int) []freeWindow {
+	var windows []freeWindow
+	var current *timeslot.Window
+	flush := func() {
+		if current != nil && current.Duration() >= needed {
+			windows = append(windows, freeWindow{Date: date, Start: current.StartClock(), End: current.EndClock(), Duration: current.Duration()})
+		}
+		current = nil
+	}
+
+	now := time.Now()
+	for _, slot := range slots {
+		window := timeslot.MustWindow(slot.Start, slot.End)
+		if slot.IsBooked || !bounds.Contains(window) || !now.Before(bookingClock(date, slot.Start)) {
+			flush()
+			continue
+		}
+		if current != nil && current.End == window.Start {
+			current.End = window.End
+			continue
+		}
+		flush()
+		current = &window
+	}
+	flush()
+	return windows
+}
+
+// 空闲时间总分钟数
+func totalFreeMinutes(windows []freeWindow) int {
+	total := 0
+	for _, window := range windows {
+		total += window.Duration
+	}
+	return total
+}
+
+// 一组会议室在日期范围内的占用情况，预定、关闭时间段、候补认领和节假日各查询一次
+type roomOccupancy struct {
+	bookings  map[uint]map[string][]models.Booking
+	blackouts map[uint][]models.RoomBlackout
+	offers    map[uint]map[string][]models.WaitlistEntry
+	calendar  map[string]*models.CalendarDay
+}
+
+// 加载会议室在日期范围内的占用情况，exceptMemberID 的候补认领不视为占用
+func loadRoomOccupancy(roomIDs []uint, startDate, endDate string, exceptMemberID uint) (*roomOccupancy, error) {
+	occupancy := &roomOccupancy{
+		bookings:  make(map[uint]map[string][]models.Booking),
+		blackouts: make(map[uint][]models.RoomBlackout),
+		offers:    make(map[uint]map[string][]models.WaitlistEntry),
+		calendar:  make(map[string]*models.CalendarDay),
+	}
+
+	var bookings []models.Booking
+	if err := database.DB.Preload("Member").Preload("BookingUsers").
+		Where("room_id IN ? AND date BETWEEN ? AND ? AND status IN ?", roomIDs, startDate, endDate, models.HoldingStatuses).
+		Order("start_time asc").Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+	for _, booking := range bookings {
+		if occupancy.bookings[booking.RoomID] == nil {
+			occupancy.bookings[booking.RoomID] = make(map[string][]models.Booking)
+		}
+		occupancy.bookings[booking.RoomID][booking.Date] = append(occupancy.bookings[booking.RoomID][booking.Date], booking)
+	}
+
+	var blackouts []models.RoomBlackout
+	if err := database.DB.Where("room_id IN ? AND start_date <= ? AND end_date >= ?", roomIDs, endDate, startDate).Find(&blackouts).Error; err != nil {
+		return nil, err
+	}
+	for _, blackout := range blackouts {
+		occupancy.blackouts[blackout.RoomID] = append(occupancy.blackouts[blackout.RoomID], blackout)
+	}
+
+	var offers []models.WaitlistEntry
+	if err := database.DB.Where("room_id IN ? AND date BETWEEN ? AND ? AND status = ? AND offer_expires_at > ? AND member_id <> ?",
+		roomIDs, startDate, endDate, "offered", time.Now(), exceptMemberID).Find(&offers).Error; err != nil {
+		return nil, err
+	}
+	for _, offer := range offers {
+		if occupancy.offers[offer.RoomID] == nil {
+			occupancy.offers[offer.RoomID] = make(map[string][]models.WaitlistEntry)
+		}
+		occupancy.offers[offer.RoomID][offer.Date] = append(occupancy.offers[offer.RoomID][offer.Date], offer)
+	}
+
+	var calendarDays []models.CalendarDay
+	if err := database.DB.Where("date BETWEEN ? AND ?", startDate, endDate).Find(&calendarDays).Error; err != nil {
+		return nil, err
+	}
+	for i := range calendarDays {
+		occupancy.calendar[calendarDays[i].Date] = &calendarDays[i]
+	}
+	return occupancy, nil
+}
+
+// 会议室当天的预定
+func (o *roomOccupancy) bookingsOn(roomID uint, date string) []models.Booking {
+	return o.bookings[roomID][date]
+}
+
+// 判断日期是否为非工作日，返回节假日名称
+func (o *roomOccupancy) nonWorkingDay(date string) (bool, string) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return false, ""
+	}
+	return calendarNonWorkingDay(day, o.calendar[date])
+}
+
+// 按与 GetAvailableSlots 相同的规则生成会议室当天的时间段并标记占用状态
+func (o *roomOccupancy) slots(room *models.Room, date string) []models.TimeSlot {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil
+	}
+	slots := generateTimeSlots(room, day)
+	slots = markBookedSlots(slots, o.bookings[room.ID][date])
+	slots = markBlockedSlots(slots, o.blackouts[room.ID], date)
+	slots = markHeldSlots(slots, o.offers[room.ID][date])
+
+	// 非工作日拒绝预定的会议室全部标记为不可预定
+	if room.HolidayPolicy == models.HolidayReject {
+		if closed, _ := o.nonWorkingDay(date); closed {
+			for i := range slots {
+				slots[i].IsBooked = true
+				slots[i].IsBlocked = true
+			}
+		}
+	}
+	return slots
+}
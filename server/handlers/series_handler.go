@@ -0,0 +1,859 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"roomly/database"
+	"roomly/middleware"
+	"roomly/models"
+	"roomly/notify"
+	"roomly/outbox"
+	"roomly/policy"
+	"roomly/timeslot"
+	"roomly/webhook"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 周期预定中单次发生的冲突信息：有 error 的发生被跳过，只有 warning 的发生仍会创建（参会人员同一时间已有其他会议）
+type seriesConflict struct {
+	Date              string              `json:"date"`
+	Error             string              `json:"error,omitempty"`
+	Warning           string              `json:"warning,omitempty"`
+	AttendeeConflicts []attendeeConflict  `json:"attendee_conflicts,omitempty"` // 同一时间已有其他会议的参会人员
+	quota             *quotaExceededError // 超出预定人的配额
+}
+
+// 创建周期预定
+func CreateBookingSeries(c *gin.Context) {
+	var request models.BookingSeriesRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 验证日期格式
+	startDate, err := time.Parse("2006-01-02", request.StartDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format, use YYYY-MM-DD"})
+		return
+	}
+	if request.Until != "" {
+		if _, err := time.Parse("2006-01-02", request.Until); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid until format, use YYYY-MM-DD"})
+			return
+		}
+		if request.Until < request.StartDate {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until must not be before start_date"})
+			return
+		}
+	}
+
+	// 首次发生必须在预定期限内，后续发生随时间推移逐步展开
+	if startDate.After(time.Now().AddDate(0, 0, maxAdvanceDays)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot book more than 30 days in advance"})
+		return
+	}
+
+	// 验证周期规则
+	switch request.Freq {
+	case models.FreqDaily, models.FreqWeekly, models.FreqMonthly:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "freq must be one of daily, weekly, monthly"})
+		return
+	}
+	if request.Interval < 0 || request.Count < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "interval and count must not be negative"})
+		return
+	}
+	if request.Interval == 0 {
+		request.Interval = 1
+	}
+	var weekdays []string
+	for _, code := range request.ByWeekday {
+		if !models.IsValidWeekdayCode(code) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid by_weekday value: " + code})
+			return
+		}
+		weekdays = append(weekdays, strings.ToUpper(code))
+	}
+
+	room, err := loadRoom(request.RoomID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		return
+	}
+	if !room.IsOpen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Room is closed"})
+		return
+	}
+
+	// 验证时间段连续性
+	window, err := timeslot.Span(request.TimeSlots, room.SlotLength())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errSlotsNotConsecutive.Error()})
+		return
+	}
+
+	// 检查参会人数是否超过会议室容量，所有发生的参会人员相同只需检查一次
+	var warnings []string
+	warning, err := checkCapacity(room, middleware.CurrentMember(c), request.BookingUsers, request.CapacityOverride)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, capacityError(room, request.BookingUsers, err, nil))
+		return
+	}
+	if warning != "" {
+		warnings = append(warnings, warning)
+	}
+
+	series := models.BookingSeries{
+		RoomID:    request.RoomID,
+		MemberID:  middleware.CurrentMember(c).ID,
+		Freq:      request.Freq,
+		Interval:  request.Interval,
+		ByWeekday: strings.Join(weekdays, ","),
+		StartDate: request.StartDate,
+		Until:     request.Until,
+		Count:     request.Count,
+		StartTime: window.StartClock(),
+		EndTime:   window.EndClock(),
+		Reason:    request.Reason,
+		Status:    "active",
+	}
+
+	// 预先检查预定期限内的发生，全部冲突时不创建周期预定
+	horizon := time.Now().AddDate(0, 0, maxAdvanceDays).Format("2006-01-02")
+	dates := series.Occurrences(time.Now().Format("2006-01-02"), horizon)
+	if len(dates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The recurrence rule produces no upcoming occurrences"})
+		return
+	}
+	var conflicts []seriesConflict
+	for _, date := range dates {
+		day, _ := time.Parse("2006-01-02", date)
+		if err := checkBookingWindow(room, day, window); err != nil {
+			conflicts = append(conflicts, seriesConflict{Date: date, Error: err.Error()})
+		} else if _, err := checkHoliday(database.DB, room, date); err != nil {
+			conflicts = append(conflicts, seriesConflict{Date: date, Error: err.Error()})
+		} else if blackout := findBlackout(database.DB, room.ID, date, window); blackout != nil {
+			conflicts = append(conflicts, seriesConflict{Date: date, Error: blackoutError(blackout).Error()})
+		} else if !areSlotsAvailable(series.RoomID, date, window) {
+			conflicts = append(conflicts, seriesConflict{Date: date, Error: "Some time slots are already booked"})
+		}
+	}
+	if len(conflicts) == len(dates) {
+		c.JSON(http.StatusConflict, gin.H{"error": "All occurrences conflict with existing bookings", "conflicts": conflicts})
+		return
+	}
+
+	var userIDs []int
+	var attendeeNames []string
+	for _, user := range request.BookingUsers {
+		userIDs = append(userIDs, int(user.Userid))
+		attendeeNames = append(attendeeNames, user.Nickname)
+	}
+	adminIDs := getRoomAdminIDs(series.RoomID)
+
+	// 周期预定、参会人员和所有发生在同一事务中创建，单个发生冲突时只回滚该发生
+	var created []models.Booking
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(&series).Error; err != nil {
+			return err
+		}
+		for _, user := range request.BookingUsers {
+			seriesUser := models.BookingSeriesUser{
+				SeriesID: series.ID,
+				Userid:   user.Userid,
+				Nickname: user.Nickname,
+			}
+			if err := tx.Create(&seriesUser).Error; err != nil {
+				return err
+			}
+			series.Users = append(series.Users, seriesUser)
+		}
+
+		var err error
+		created, conflicts, err = materializeSeries(tx, &series, horizon, request.BlockOwnConflict)
+		if err != nil {
+			return err
+		}
+		// 每个发生都计入预定人的配额，任一发生超出配额时不创建周期预定
+		for _, conflict := range conflicts {
+			if conflict.quota != nil {
+				return conflict.quota
+			}
+		}
+		if len(created) == 0 {
+			return errSlotsTaken
+		}
+
+		// 会议通知与周期预定在同一事务中写入
+		if bookingStatus(room, middleware.CurrentMember(c)) == "pending" {
+			// 待审批的周期预定只通知会议室管理员，各发生审批通过后再通知参会人员
+			return outbox.Publish(tx, notify.Event{
+				Type:      "approval_request",
+				AdminIDs:  adminIDs,
+				Token:     middleware.Token(c),
+				Date:      series.StartDate,
+				StartTime: series.StartTime,
+				EndTime:   series.EndTime,
+				RoomName:  room.Name,
+				Reason:    request.Reason,
+				Attendees: strings.Join(attendeeNames, "、"),
+				Series:    seriesRecurrence(&series, ""),
+			})
+		}
+		return outbox.Publish(tx, notify.Event{
+			Type:      "remind",
+			UserIDs:   userIDs,
+			AdminIDs:  adminIDs,
+			Token:     middleware.Token(c),
+			Date:      series.StartDate,
+			StartTime: series.StartTime,
+			EndTime:   series.EndTime,
+			RoomName:  room.Name,
+			Reason:    request.Reason,
+			Attendees: strings.Join(attendeeNames, "、"),
+			Series:    seriesRecurrence(&series, ""),
+		})
+	})
+	var exceeded *quotaExceededError
+	if errors.As(err, &exceeded) {
+		c.JSON(http.StatusBadRequest, quotaError(exceeded.policy, exceeded.violation))
+		return
+	}
+	if errors.Is(err, errSlotsTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "All occurrences conflict with existing bookings", "conflicts": conflicts})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking series"})
+		return
+	}
+
+	go outbox.Deliver()
+	go webhook.Deliver()
+
+	database.DB.Preload("Room").Preload("Member").Preload("Users").First(&series, series.ID)
+
+	response := gin.H{
+		"series":             series,
+		"bookings":           created,
+		"conflicts":          conflicts,
+		"materialized_until": series.MaterializedUntil,
+		"finished":           series.IsFinished(series.MaterializedUntil),
+	}
+	for _, conflict := range conflicts {
+		if len(conflict.AttendeeConflicts) > 0 && conflict.Error == "" {
+			warnings = append(warnings, "Some attendees already have overlapping bookings")
+			break
+		}
+	}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// 获取周期预定及其所有发生；任一发生为无权查看的私密会议时，同时隐藏周期预定的理由和参会人员
+func GetBookingSeries(c *gin.Context) {
+	id := c.Param("id")
+	var series models.BookingSeries
+	if err := database.DB.Preload("Room").Preload("Member").Preload("Users").First(&series, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking series not found"})
+		return
+	}
+
+	var bookings []models.Booking
+	if err := database.DB.Where("series_id = ?", series.ID).Preload("BookingUsers").Order("date asc").Find(&bookings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch series bookings"})
+		return
+	}
+	member := middleware.CurrentMember(c)
+	for i := range bookings {
+		if redactBooking(member, &bookings[i]) {
+			series.Reason = ""
+			series.Users = []models.BookingSeriesUser{}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"series":   series,
+		"bookings": bookings,
+	})
+}
+
+// 修改周期预定中的发生，scope: this 仅本次，following 本次及以后，all 全部未开始的发生
+func UpdateBookingSeries(c *gin.Context) {
+	id := c.Param("id")
+
+	var request models.BookingSeriesUpdateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Scope != "this" && request.Scope != "following" && request.Scope != "all" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be one of this, following, all"})
+		return
+	}
+
+	var booking models.Booking
+	if err := database.DB.Preload("BookingUsers").Preload("Room").First(&booking, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+	if err := policy.CanManageBooking(middleware.CurrentMember(c), &booking); err != nil {
+		middleware.AbortWithPolicyError(c, err)
+		return
+	}
+	if booking.SeriesID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking is not part of a series"})
+		return
+	}
+	if booking.Status != "active" && booking.Status != "pending" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only active or pending bookings can be modified"})
+		return
+	}
+
+	var series models.BookingSeries
+	if err := database.DB.Preload("Users").First(&series, *booking.SeriesID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking series not found"})
+		return
+	}
+
+	// 确定受影响的发生
+	var targets []models.Booking
+	var err error
+	switch request.Scope {
+	case "this":
+		targets = []models.Booking{booking}
+	case "following":
+		err = database.DB.Preload("BookingUsers").Preload("Room").Where("series_id = ? AND status IN ? AND date >= ?", series.ID, models.HoldingStatuses, booking.Date).Find(&targets).Error
+	case "all":
+		err = database.DB.Preload("BookingUsers").Preload("Room").Where("series_id = ? AND status IN ? AND date >= ?", series.ID, models.HoldingStatuses, time.Now().Format("2006-01-02")).Find(&targets).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch series occurrences"})
+		return
+	}
+
+	// 冲突检查时排除受影响的发生本身
+	var excludeIDs []uint
+	for _, target := range targets {
+		excludeIDs = append(excludeIDs, target.ID)
+	}
+	var window timeslot.Window
+	if len(request.TimeSlots) > 0 {
+		room, err := loadRoom(series.RoomID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+			return
+		}
+		window, err = timeslot.Span(request.TimeSlots, room.SlotLength())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errSlotsNotConsecutive.Error()})
+			return
+		}
+
+		// 单独改过会议室的发生按其所在会议室的规则校验
+		rooms := map[uint]*models.Room{room.ID: room}
+		var conflicts []seriesConflict
+		for _, target := range targets {
+			targetRoom, ok := rooms[target.RoomID]
+			if !ok {
+				if targetRoom, err = loadRoom(target.RoomID); err != nil {
+					conflicts = append(conflicts, seriesConflict{Date: target.Date, Error: "Room not found"})
+					continue
+				}
+				rooms[target.RoomID] = targetRoom
+			}
+			day, _ := time.Parse("2006-01-02", target.Date)
+			if err := checkBookingWindow(targetRoom, day, window); err != nil {
+				conflicts = append(conflicts, seriesConflict{Date: target.Date, Error: err.Error()})
+			} else if blackout := findBlackout(database.DB, target.RoomID, target.Date, window); blackout != nil {
+				conflicts = append(conflicts, seriesConflict{Date: target.Date, Error: blackoutError(blackout).Error()})
+			} else if !areSlotsAvailable(target.RoomID, target.Date, window, excludeIDs...) {
+				conflicts = append(conflicts, seriesConflict{Date: target.Date, Error: "Some time slots are already booked"})
+			}
+		}
+		if len(conflicts) > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Some occurrences conflict with existing bookings", "conflicts": conflicts})
+			return
+		}
+	}
+
+	// 修改参会人员时按每个发生所在会议室的容量检查参会人数
+	var warnings []string
+	if request.BookingUsers != nil {
+		checked := make(map[uint]bool)
+		for _, target := range targets {
+			if checked[target.RoomID] {
+				continue
+			}
+			checked[target.RoomID] = true
+			warning, err := checkCapacity(&target.Room, middleware.CurrentMember(c), request.BookingUsers, request.CapacityOverride)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, capacityError(&target.Room, request.BookingUsers, err, nil))
+				return
+			}
+			if warning != "" {
+				warnings = append(warnings, warning)
+			}
+		}
+	}
+
+	// 调整了时间或参会人员时逐个检查发生的参会人员冲突，与创建周期预定一样按发生报告；
+	// 预定人自己冲突且选择拒绝时不修改
+	var attendeeWarnings []seriesConflict
+	if len(request.TimeSlots) > 0 || request.BookingUsers != nil {
+		var owner models.Member
+		database.DB.First(&owner, booking.MemberID)
+		var blocked []seriesConflict
+		for _, target := range targets {
+			users := target.BookingUsers
+			if request.BookingUsers != nil {
+				users = request.BookingUsers
+			}
+			targetWindow := window
+			if len(request.TimeSlots) == 0 {
+				targetWindow = timeslot.MustWindow(target.StartTime, target.EndTime)
+			}
+			found, err := findAttendeeConflicts(database.DB, users, &owner, target.Date, targetWindow, target.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check attendee conflicts"})
+				return
+			}
+			if len(found) == 0 {
+				continue
+			}
+			if request.BlockOwnConflict && hasOrganizerConflict(found, &owner) {
+				blocked = append(blocked, seriesConflict{Date: target.Date, Error: "The organizer already has an overlapping booking", AttendeeConflicts: found})
+			} else {
+				attendeeWarnings = append(attendeeWarnings, seriesConflict{Date: target.Date, Warning: "Some attendees already have overlapping bookings", AttendeeConflicts: found})
+			}
+		}
+		if len(blocked) > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "The organizer already has overlapping bookings", "conflicts": blocked})
+			return
+		}
+	}
+
+	// 拆分周期规则、更新规则和所有受影响的发生在同一事务中完成，任一冲突则整体回滚
+	memberID := middleware.CurrentMember(c).ID
+	// 通知以当前发生为准，当前发生不在范围内时（如已过去）取第一个发生
+	anchor := booking
+	var anchorChanges []models.BookingChange
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// 本次及以后：从当前发生处拆分出新的周期预定
+		if request.Scope == "following" && booking.Date > series.StartDate {
+			before := series.Occurrences(series.StartDate, previousDate(booking.Date))
+			next := series
+			next.ID = 0
+			next.StartDate = booking.Date
+			next.Users = nil
+			if next.ByWeekday == "" {
+				next.ByWeekday = weekdayCodesOf(series.Weekdays())
+			}
+			if next.Count > 0 {
+				next.Count -= len(before)
+			}
+			if err := tx.Omit(clause.Associations).Create(&next).Error; err != nil {
+				return err
+			}
+			for _, user := range series.Users {
+				if err := tx.Create(&models.BookingSeriesUser{SeriesID: next.ID, Userid: user.Userid, Nickname: user.Nickname}).Error; err != nil {
+					return err
+				}
+			}
+			series.Until = previousDate(booking.Date)
+			if err := tx.Omit(clause.Associations).Save(&series).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Booking{}).Where("id IN ?", excludeIDs).Update("series_id", next.ID).Error; err != nil {
+				return err
+			}
+			series = next
+		}
+
+		// 更新周期规则本身，使后续展开的发生使用新的设置
+		if request.Scope != "this" {
+			if len(request.TimeSlots) > 0 {
+				series.StartTime = window.StartClock()
+				series.EndTime = window.EndClock()
+			}
+			if request.Reason != "" {
+				series.Reason = request.Reason
+			}
+			if err := tx.Omit(clause.Associations).Save(&series).Error; err != nil {
+				return err
+			}
+			if request.BookingUsers != nil {
+				if err := tx.Where("series_id = ?", series.ID).Delete(&models.BookingSeriesUser{}).Error; err != nil {
+					return err
+				}
+				for _, user := range request.BookingUsers {
+					if err := tx.Create(&models.BookingSeriesUser{SeriesID: series.ID, Userid: user.Userid, Nickname: user.Nickname}).Error; err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		// 先释放所有受影响发生的时间段，避免发生之间互相冲突
+		if err := releaseSlots(tx, excludeIDs...); err != nil {
+			return err
+		}
+		var owner models.Member
+		if err := tx.Preload("ManagedRooms").First(&owner, series.MemberID).Error; err != nil {
+			return err
+		}
+
+		// 应用到受影响的发生，并记录每个发生的变更
+		for _, target := range targets {
+			updated := target
+			if len(request.TimeSlots) > 0 {
+				updated.StartTime = window.StartClock()
+				updated.EndTime = window.EndClock()
+			}
+			if request.Reason != "" {
+				updated.Reason = request.Reason
+			}
+			// 调整时间后按预定人的配额策略重新检查每个发生
+			if len(request.TimeSlots) > 0 {
+				if err := enforceQuota(tx, &target.Room, &owner, target.Date, window, target.ID); err != nil {
+					return err
+				}
+			}
+			changes := diffBooking(&target, &updated, request.BookingUsers)
+			if len(changes) == 0 {
+				if err := reserveSlots(tx, &updated); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := applyBookingUpdate(tx, &updated, request.BookingUsers, changes, memberID); err != nil {
+				return err
+			}
+			if target.ID == booking.ID || anchorChanges == nil {
+				anchor = target
+				anchorChanges = changes
+			}
+		}
+		if len(anchorChanges) == 0 {
+			return nil
+		}
+
+		// 写入一条汇总的变更通知
+		var updated models.Booking
+		if err := tx.Preload("Room").Preload("BookingUsers").First(&updated, anchor.ID).Error; err != nil {
+			return err
+		}
+		var recurrence *notify.Recurrence
+		switch request.Scope {
+		case "following":
+			recurrence = seriesRecurrence(&series, anchor.Date)
+		case "all":
+			recurrence = seriesRecurrence(&series, "")
+		}
+		return notifyBookingChange(tx, middleware.Token(c), &anchor, &updated, recurrence, anchorChanges)
+	})
+	var exceeded *quotaExceededError
+	if errors.As(err, &exceeded) {
+		c.JSON(http.StatusBadRequest, quotaError(exceeded.policy, exceeded.violation))
+		return
+	}
+	if errors.Is(err, errSlotsTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Some occurrences conflict with existing bookings"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking series"})
+		return
+	}
+	go outbox.Deliver()
+	go webhook.Deliver()
+
+	database.DB.Preload("Room").Preload("Member").Preload("Users").First(&series, series.ID)
+	response := gin.H{
+		"series":  series,
+		"updated": len(targets),
+	}
+	if len(attendeeWarnings) > 0 {
+		response["conflicts"] = attendeeWarnings
+		warnings = append(warnings, "Some attendees already have overlapping bookings")
+	}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// 定时任务：随时间推移展开周期预定，保持预定期限内的发生已创建
+func ExtendBookingSeries() {
+	horizon := time.Now().AddDate(0, 0, maxAdvanceDays).Format("2006-01-02")
+
+	var seriesList []models.BookingSeries
+	database.DB.Preload("Users").Where("status = ?", "active").Find(&seriesList)
+
+	for i := range seriesList {
+		series := &seriesList[i]
+		if series.IsFinished(series.MaterializedUntil) || series.MaterializedUntil >= horizon {
+			continue
+		}
+		var conflicts []seriesConflict
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			var err error
+			_, conflicts, err = materializeSeries(tx, series, horizon, false)
+			return err
+		})
+		if err != nil {
+			log.Printf("展开周期预定%d失败: %v", series.ID, err)
+			continue
+		}
+		for _, conflict := range conflicts {
+			if conflict.Error != "" {
+				log.Printf("周期预定%d在%s冲突，已跳过: %s", series.ID, conflict.Date, conflict.Error)
+			}
+		}
+	}
+}
+
+// 将周期预定展开到指定日期，已展开过的日期不会重复创建（即使其发生已被单独取消）；
+// 每个发生在独立的保存点中创建，冲突的发生被跳过并返回，参会人员同一时间已有其他会议的发生仍会创建并返回提示；
+// blockOwnConflict 时预定人自己同一时间已有会议的发生也被跳过
+func materializeSeries(tx *gorm.DB, series *models.BookingSeries, until string, blockOwnConflict bool) ([]models.Booking, []seriesConflict, error) {
+	from := series.StartDate
+	if series.MaterializedUntil != "" {
+		from = nextDate(series.MaterializedUntil)
+	}
+	if today := time.Now().Format("2006-01-02"); from < today {
+		from = today
+	}
+
+	var room models.Room
+	if err := tx.Preload("BusinessHours").First(&room, series.RoomID).Error; err != nil {
+		return nil, nil, err
+	}
+	// 会议室已关闭时暂停展开，重新开放后从未展开的日期继续
+	if !room.IsOpen {
+		return nil, nil, nil
+	}
+	// 需要审批的会议室中，每个发生都单独等待审批
+	var member models.Member
+	if err := tx.Preload("ManagedRooms").First(&member, series.MemberID).Error; err != nil {
+		return nil, nil, err
+	}
+	status := bookingStatus(&room, &member)
+	window := timeslot.MustWindow(series.StartTime, series.EndTime)
+
+	var created []models.Booking
+	var conflicts []seriesConflict
+	for _, date := range series.Occurrences(from, until) {
+		// 不在营业时间内、非工作日被拒绝或会议室关闭的发生直接跳过
+		day, _ := time.Parse("2006-01-02", date)
+		if err := checkBookingWindow(&room, day, window); err != nil {
+			conflicts = append(conflicts, seriesConflict{Date: date, Error: err.Error()})
+			continue
+		}
+		if _, err := checkHoliday(tx, &room, date); err != nil {
+			conflicts = append(conflicts, seriesConflict{Date: date, Error: err.Error()})
+			continue
+		}
+		if blackout := findBlackout(tx, room.ID, date, window); blackout != nil {
+			conflicts = append(conflicts, seriesConflict{Date: date, Error: blackoutError(blackout).Error()})
+			continue
+		}
+		// 每个发生都计入预定人的配额，已创建的发生在同一事务中可见
+		if quotaPolicy, violation := checkQuota(tx, &room, &member, date, window, 0); violation != nil {
+			exceeded := &quotaExceededError{policy: quotaPolicy, violation: violation}
+			conflicts = append(conflicts, seriesConflict{Date: date, Error: exceeded.Error(), quota: exceeded})
+			continue
+		}
+
+		seriesID := series.ID
+		booking := models.Booking{
+			RoomID:    series.RoomID,
+			MemberID:  series.MemberID,
+			Date:      date,
+			StartTime: series.StartTime,
+			EndTime:   series.EndTime,
+			Reason:    series.Reason,
+			Status:    status,
+			SeriesID:  &seriesID,
+		}
+		var users []models.BookingUser
+		for _, user := range series.Users {
+			users = append(users, models.BookingUser{Userid: user.Userid, Nickname: user.Nickname})
+		}
+
+		// 检查参会人员和预定人同一时间是否已有其他会议
+		attendeeConflicts, err := findAttendeeConflicts(tx, users, &member, date, window, 0)
+		if err != nil {
+			return created, conflicts, err
+		}
+		if blockOwnConflict && hasOrganizerConflict(attendeeConflicts, &member) {
+			conflicts = append(conflicts, seriesConflict{Date: date, Error: "You already have an overlapping booking", AttendeeConflicts: attendeeConflicts})
+			continue
+		}
+
+		err = tx.Transaction(func(tx *gorm.DB) error {
+			return insertBooking(tx, &booking, users)
+		})
+		if errors.Is(err, errSlotsTaken) {
+			conflicts = append(conflicts, seriesConflict{Date: date, Error: "Some time slots are already booked"})
+			continue
+		}
+		if err != nil {
+			return created, conflicts, err
+		}
+		created = append(created, booking)
+		if len(attendeeConflicts) > 0 {
+			conflicts = append(conflicts, seriesConflict{Date: date, Warning: "Some attendees already have overlapping bookings", AttendeeConflicts: attendeeConflicts})
+		}
+	}
+
+	if until > series.MaterializedUntil {
+		series.MaterializedUntil = until
+	}
+	if err := tx.Model(series).Update("materialized_until", series.MaterializedUntil).Error; err != nil {
+		return created, conflicts, err
+	}
+	return created, conflicts, nil
+}
+
+// 取消周期预定中本次及以后（following）或全部未开始（all）的发生并写入取消通知，返回被取消的发生
+func cancelSeriesOccurrences(token string, booking models.Booking, scope string, cancelReason string) (*models.BookingSeries, []models.Booking, error) {
+	var series models.BookingSeries
+	if err := database.DB.Preload("Room").First(&series, *booking.SeriesID).Error; err != nil {
+		return nil, nil, err
+	}
+
+	fromDate := booking.Date
+	if scope == "all" {
+		fromDate = time.Now().Format("2006-01-02")
+	}
+
+	adminIDs := getRoomAdminIDs(booking.RoomID)
+	var cancelled []models.Booking
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("series_id = ? AND status IN ? AND date >= ?", series.ID, models.HoldingStatuses, fromDate).
+			Find(&cancelled).Error; err != nil {
+			return err
+		}
+		if len(cancelled) > 0 {
+			var bookingIDs []uint
+			for _, occurrence := range cancelled {
+				bookingIDs = append(bookingIDs, occurrence.ID)
+			}
+			result := tx.Model(&models.Booking{}).
+				Where("id IN ?", bookingIDs).
+				Updates(map[string]interface{}{"status": "cancelled", "cancel_reason": cancelReason})
+			if result.Error != nil {
+				return result.Error
+			}
+			if err := releaseSlots(tx, bookingIDs...); err != nil {
+				return err
+			}
+			if err := webhook.Publish(tx, webhook.EventCancelled, bookingIDs...); err != nil {
+				return err
+			}
+		}
+
+		// 截断或结束周期规则，避免后续再展开
+		if scope == "all" || booking.Date <= series.StartDate {
+			series.Status = "cancelled"
+		} else {
+			series.Until = previousDate(booking.Date)
+		}
+		if err := tx.Omit(clause.Associations).Save(&series).Error; err != nil {
+			return err
+		}
+		if len(booking.BookingUsers) == 0 {
+			return nil
+		}
+
+		// 写入一条汇总的取消通知
+		var userIDs []int
+		var attendeeNames []string
+		for _, user := range booking.BookingUsers {
+			userIDs = append(userIDs, int(user.Userid))
+			attendeeNames = append(attendeeNames, user.Nickname)
+		}
+		var from string
+		if scope == "following" {
+			from = booking.Date
+		}
+		return outbox.Publish(tx, notify.Event{
+			Type:      "cancel",
+			UserIDs:   userIDs,
+			AdminIDs:  adminIDs,
+			Token:     token,
+			Date:      booking.Date,
+			StartTime: booking.StartTime,
+			EndTime:   booking.EndTime,
+			RoomName:  series.Room.Name,
+			Reason:    booking.Reason,
+			Attendees: strings.Join(attendeeNames, "、"),
+			Series:    seriesRecurrence(&series, from),
+			Content:   []string{cancelReason},
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &series, cancelled, nil
+}
+
+// 周期规则的通知数据，from 不为空时表示只涉及该日期起的后续会议
+func seriesRecurrence(series *models.BookingSeries, from string) *notify.Recurrence {
+	recurrence := &notify.Recurrence{
+		Freq:      series.Freq,
+		Interval:  series.Interval,
+		StartDate: series.StartDate,
+		Until:     series.Until,
+		Count:     series.Count,
+		From:      from,
+	}
+	if recurrence.Interval < 1 {
+		recurrence.Interval = 1
+	}
+	switch series.Freq {
+	case models.FreqWeekly:
+		for _, day := range series.Weekdays() {
+			recurrence.Weekdays = append(recurrence.Weekdays, int(day))
+		}
+	case models.FreqMonthly:
+		if start, err := time.Parse("2006-01-02", series.StartDate); err == nil {
+			recurrence.Day = start.Day()
+		}
+	}
+	return recurrence
+}
+
+// 将星期列表转换为逗号分隔的缩写
+func weekdayCodesOf(days []time.Weekday) string {
+	codes := []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+	var parts []string
+	for _, day := range days {
+		parts = append(parts, codes[day])
+	}
+	return strings.Join(parts, ",")
+}
+
+// 获取前一天日期
+func previousDate(date string) string {
+	d, _ := time.Parse("2006-01-02", date)
+	return d.AddDate(0, 0, -1).Format("2006-01-02")
+}
+
+// 获取后一天日期
+func nextDate(date string) string {
+	d, _ := time.Parse("2006-01-02", date)
+	return d.AddDate(0, 0, 1).Format("2006-01-02")
+}
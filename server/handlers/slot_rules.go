@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"roomly/database"
+	"roomly/models"
+	"roomly/timeslot"
+)
+
+// 预定时间校验错误，错误信息直接返回给客户端
+var (
+	errSlotsNotConsecutive = errors.New("Time slots must be consecutive")
+	errOutsideHours        = errors.New("Time slots are outside the room's business hours")
+	errSlotsMisaligned     = errors.New("Time slots do not match the room's slot length")
+)
+
+// 将时间段开始时间列表解析为预定区间，并按会议室规则校验
+func resolveBookingWindow(room *models.Room, date string, timeSlots []string) (timeslot.Window, error) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return timeslot.Window{}, errors.New("Invalid date format, use YYYY-MM-DD")
+	}
+	window, err := timeslot.Span(timeSlots, room.SlotLength())
+	if err != nil {
+		return timeslot.Window{}, errSlotsNotConsecutive
+	}
+	return window, checkBookingWindow(room, day, window)
+}
+
+// 校验预定区间是否在会议室营业时间内、与时间段对齐且满足时长限制
+func checkBookingWindow(room *models.Room, day time.Time, window timeslot.Window) error {
+	hours, ok := room.OpeningHours(day.Weekday())
+	if !ok || !hours.Contains(window) {
+		return errOutsideHours
+	}
+	length := room.SlotLength()
+	if (window.Start-hours.Start)%length != 0 || window.Duration()%length != 0 {
+		return errSlotsMisaligned
+	}
+	if room.MinDuration > 0 && window.Duration() < room.MinDuration {
+		return fmt.Errorf("Booking must be at least %d minutes", room.MinDuration)
+	}
+	if room.MaxDuration > 0 && window.Duration() > room.MaxDuration {
+		return fmt.Errorf("Booking must not exceed %d minutes", room.MaxDuration)
+	}
+	return nil
+}
+
+// 加载会议室及其营业时间
+func loadRoom(roomID uint) (*models.Room, error) {
+	var room models.Room
+	if err := database.DB.Preload("BusinessHours").First(&room, roomID).Error; err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
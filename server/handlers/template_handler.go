@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"roomly/database"
+	"roomly/message"
+	"roomly/middleware"
+	"roomly/models"
+	"roomly/notify"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 消息模板，customized 表示管理员修改过，否则为内置模板
+type messageTemplate struct {
+	Event      string     `json:"event"`
+	Audience   string     `json:"audience"`
+	Locale     string     `json:"locale"`
+	Body       string     `json:"body"`
+	Customized bool       `json:"customized"`
+	UpdatedAt  *time.Time `json:"updated_at"`
+}
+
+// 获取所有消息模板，可按 event、audience、locale 筛选
+func GetMessageTemplates(c *gin.Context) {
+	event := c.Query("event")
+	audience := c.Query("audience")
+	locale := c.Query("locale")
+
+	var customs []models.MessageTemplate
+	if err := database.DB.Find(&customs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch message templates"})
+		return
+	}
+	customized := make(map[string]*models.MessageTemplate)
+	for i := range customs {
+		custom := &customs[i]
+		customized[custom.Event+"/"+custom.Audience+"/"+custom.Locale] = custom
+	}
+
+	templates := []messageTemplate{}
+	for _, key := range message.Keys() {
+		if (event != "" && key.Event != event) || (audience != "" && key.Audience != audience) {
+			continue
+		}
+		for _, loc := range message.Locales {
+			if locale != "" && loc != locale {
+				continue
+			}
+			templates = append(templates, effectiveTemplate(key.Event, key.Audience, loc, customized[key.Event+"/"+key.Audience+"/"+loc]))
+		}
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+// 获取单个消息模板
+func GetMessageTemplate(c *gin.Context) {
+	event, audience, locale, ok := templateKey(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, effectiveTemplate(event, audience, locale, customTemplate(event, audience, locale)))
+}
+
+// 修改消息模板，模板需能用示例预定渲染
+func UpdateMessageTemplate(c *gin.Context) {
+	event, audience, locale, ok := templateKey(c)
+	if !ok {
+		return
+	}
+	var request struct {
+		Body string `json:"body" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := message.Execute(request.Body, locale, message.Sample(event)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template: " + err.Error()})
+		return
+	}
+
+	custom := customTemplate(event, audience, locale)
+	if custom == nil {
+		custom = &models.MessageTemplate{Event: event, Audience: audience, Locale: locale}
+	}
+	custom.Body = request.Body
+	custom.UpdatedBy = middleware.CurrentMember(c).ID
+	if err := database.DB.Save(custom).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update message template"})
+		return
+	}
+	c.JSON(http.StatusOK, effectiveTemplate(event, audience, locale, custom))
+}
+
+// 删除修改过的消息模板，恢复为内置模板
+func ResetMessageTemplate(c *gin.Context) {
+	event, audience, locale, ok := templateKey(c)
+	if !ok {
+		return
+	}
+	if err := database.DB.Where("event = ? AND audience = ? AND locale = ?", event, audience, locale).
+		Delete(&models.MessageTemplate{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset message template"})
+		return
+	}
+	c.JSON(http.StatusOK, effectiveTemplate(event, audience, locale, nil))
+}
+
+// 预览消息模板：body 为空时使用当前生效的模板；指定 booking_id 时用该预定渲染，否则用示例预定
+func PreviewMessageTemplate(c *gin.Context) {
+	var request struct {
+		Event     string   `json:"event" binding:"required"`
+		Audience  string   `json:"audience" binding:"required"`
+		Locale    string   `json:"locale"`
+		Body      string   `json:"body"`
+		BookingID uint     `json:"booking_id"`
+		Content   []string `json:"content"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Locale == "" {
+		request.Locale = message.DefaultLocale
+	}
+	if !message.IsKnown(request.Event, request.Audience) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown event or audience"})
+		return
+	}
+	if !message.IsLocale(request.Locale) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported locale"})
+		return
+	}
+
+	data := message.Sample(request.Event)
+	if request.BookingID != 0 {
+		var booking models.Booking
+		if err := database.DB.Preload("Room").Preload("Member").Preload("BookingUsers").First(&booking, request.BookingID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+			return
+		}
+		data.Sender = booking.Member.Name
+		data.Date = booking.Date
+		data.StartTime = booking.StartTime
+		data.EndTime = booking.EndTime
+		data.RoomName = booking.Room.Name
+		data.Reason = booking.Reason
+		data.Attendees = joinNicknames(booking.BookingUsers)
+	}
+	if request.Content != nil {
+		data.Content = request.Content
+	}
+
+	body := request.Body
+	if body == "" {
+		var err error
+		if body, err = message.Lookup(request.Event, request.Audience, request.Locale); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	text, err := message.Execute(body, request.Locale, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"subject": notify.Subject(text),
+		"text":    text,
+	})
+}
+
+// 解析并校验路径中的事件类型、接收人类型和语言
+func templateKey(c *gin.Context) (string, string, string, bool) {
+	event, audience, locale := c.Param("event"), c.Param("audience"), c.Param("locale")
+	if !message.IsKnown(event, audience) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message template not found"})
+		return "", "", "", false
+	}
+	if !message.IsLocale(locale) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported locale"})
+		return "", "", "", false
+	}
+	return event, audience, locale, true
+}
+
+// 加载管理员修改过的模板，没有时返回 nil
+func customTemplate(event, audience, locale string) *models.MessageTemplate {
+	var custom models.MessageTemplate
+	if err := database.DB.Where("event = ? AND audience = ? AND locale = ?", event, audience, locale).First(&custom).Error; err != nil {
+		return nil
+	}
+	return &custom
+}
+
+// 当前生效的模板：修改过的模板或内置模板
+func effectiveTemplate(event, audience, locale string, custom *models.MessageTemplate) messageTemplate {
+	if custom != nil {
+		return messageTemplate{Event: event, Audience: audience, Locale: locale, Body: custom.Body, Customized: true, UpdatedAt: &custom.UpdatedAt}
+	}
+	body, _ := message.Default(event, audience, locale)
+	return messageTemplate{Event: event, Audience: audience, Locale: locale, Body: body}
+}
@@ -2,7 +2,11 @@ package handlers
 
 import (
 	"net/http"
-	"roomly/models"
+	"roomly/database"
+	"roomly/middleware"
+	"roomly/notify"
+	"roomly/outbox"
+	"roomly/timeslot"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -31,19 +35,25 @@ func SendMessageToUsers(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "用户ID不能为空"})
 		return
 	}
-	// 从 header 获取 token
-	authHeader := c.GetHeader("Authorization")
-	var token string
-	if len(authHeader) > 0 {
-		// 兼容 Bearer token
-		if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-			token = authHeader[7:]
-		} else {
-			token = authHeader
-		}
+	// 获取当前请求的 token
+	token := middleware.Token(c)
+	startTime, endTime := timeSlotRange(timeSlots)
+	// 写入通知发件箱后异步发送会议通知
+	err := outbox.Publish(database.DB, notify.Event{
+		Type:      "remind",
+		UserIDs:   userIDs,
+		Token:     token,
+		Date:      date,
+		StartTime: startTime,
+		EndTime:   endTime,
+		RoomName:  roomName,
+		Reason:    reason,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notifications"})
+		return
 	}
-	// 异步发送会议通知
-	models.SendMessageWithToken(userIDs, []int{}, token, date, timeSlots, roomName, "remind", reason, "")
+	go outbox.Deliver()
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
@@ -70,19 +80,14 @@ func SendMeetingSummary(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "用户ID不能为空"})
 		return
 	}
-	// 从 header 获取 token
-	authHeader := c.GetHeader("Authorization")
-	var token string
-	if len(authHeader) > 0 {
-		// 兼容 Bearer token
-		if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-			token = authHeader[7:]
-		} else {
-			token = authHeader
-		}
+	// 获取当前请求的 token
+	token := middleware.Token(c)
+	startTime, endTime := timeSlotRange(timeSlots)
+	// 写入通知发件箱后异步发送会议纪要通知
+	if err := publishSummary(userIDs, token, date, startTime, endTime, roomName, summaryContent); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notifications"})
+		return
 	}
-	// 异步发送会议纪要通知
-	models.SendMessageWithToken(userIDs, []int{}, token, date, timeSlots, roomName, "summary", "", "", summaryContent)
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
@@ -106,17 +111,40 @@ func SendMeetingSummaryPost(c *gin.Context) {
 		return
 	}
 
-	// 从 header 获取 token
-	authHeader := c.GetHeader("Authorization")
-	var token string
-	if len(authHeader) > 0 {
-		if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-			token = authHeader[7:]
-		} else {
-			token = authHeader
-		}
-	}
+	// 获取当前请求的 token
+	token := middleware.Token(c)
+	startTime, endTime := timeSlotRange(req.TimeSlots)
 
-	models.SendMessageWithToken(req.UserIDs, []int{}, token, req.Date, req.TimeSlots, req.RoomName, "summary", "", "", req.SummaryContent)
+	if err := publishSummary(req.UserIDs, token, req.Date, startTime, endTime, req.RoomName, req.SummaryContent); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notifications"})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
+
+// 写入会议纪要通知并异步发送
+func publishSummary(userIDs []int, token, date, startTime, endTime, roomName, summaryContent string) error {
+	err := outbox.Publish(database.DB, notify.Event{
+		Type:      "summary",
+		UserIDs:   userIDs,
+		Token:     token,
+		Date:      date,
+		StartTime: startTime,
+		EndTime:   endTime,
+		RoomName:  roomName,
+		Content:   []string{summaryContent},
+	})
+	if err != nil {
+		return err
+	}
+	go outbox.Deliver()
+	return nil
+}
+
+// 将时间段开始时间列表转换为开始和结束时间，外部调用方按默认时间段长度传入
+func timeSlotRange(timeSlots []string) (string, string) {
+	if len(timeSlots) == 0 {
+		return "", ""
+	}
+	return timeSlots[0], timeslot.Add(timeSlots[len(timeSlots)-1], timeslot.DefaultLength)
+}
@@ -0,0 +1,423 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"roomly/database"
+	"roomly/middleware"
+	"roomly/models"
+	"roomly/notify"
+	"roomly/outbox"
+	"roomly/policy"
+	"roomly/timeslot"
+	"roomly/webhook"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 候补会员认领释放时间段的时限
+const waitlistClaimWindow = 30 * time.Minute
+
+// 加入候补，仅在所选时间段已被预定时可以加入
+func JoinWaitlist(c *gin.Context) {
+	var request models.WaitlistRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	requestDate, err := time.Parse("2006-01-02", request.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+		return
+	}
+	if requestDate.After(time.Now().AddDate(0, 0, maxAdvanceDays)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot book more than 30 days in advance"})
+		return
+	}
+
+	room, err := loadRoom(request.RoomID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		return
+	}
+	window, err := resolveBookingWindow(room, request.Date, request.TimeSlots)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !time.Now().Before(bookingClock(request.Date, window.StartClock())) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The selected time has already started"})
+		return
+	}
+	if areSlotsAvailable(room.ID, request.Date, window) && findWaitlistHold(database.DB, room.ID, request.Date, window, 0) == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Time slots are available, book them directly"})
+		return
+	}
+
+	member := middleware.CurrentMember(c)
+	var existing int64
+	database.DB.Model(&models.WaitlistEntry{}).
+		Where("member_id = ? AND room_id = ? AND date = ? AND start_time = ? AND end_time = ? AND status IN ?", member.ID, room.ID, request.Date, window.StartClock(), window.EndClock(), []string{"waiting", "offered"}).
+		Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Already on the waitlist for these time slots"})
+		return
+	}
+
+	entry := models.WaitlistEntry{
+		RoomID:    room.ID,
+		MemberID:  member.ID,
+		Date:      request.Date,
+		StartTime: window.StartClock(),
+		EndTime:   window.EndClock(),
+		Reason:    request.Reason,
+		AutoBook:  request.AutoBook,
+		Status:    "waiting",
+	}
+	for _, user := range request.BookingUsers {
+		entry.Users = append(entry.Users, models.WaitlistUser{Userid: user.Userid, Nickname: user.Nickname})
+	}
+	if err := database.DB.Omit("Room", "Member").Create(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join waitlist"})
+		return
+	}
+
+	// 返回排队位置
+	var position int64
+	database.DB.Model(&models.WaitlistEntry{}).
+		Where("room_id = ? AND date = ? AND status = ? AND id <= ?", room.ID, request.Date, "waiting", entry.ID).
+		Count(&position)
+
+	entry.Room = *room
+	c.JSON(http.StatusCreated, gin.H{
+		"entry":    entry,
+		"position": position,
+	})
+}
+
+// 获取候补列表：默认返回自己的候补，传入 room_id 时返回该会议室的候补（需要会议室管理权限）
+func GetWaitlist(c *gin.Context) {
+	member := middleware.CurrentMember(c)
+	db := database.DB.Model(&models.WaitlistEntry{})
+
+	if roomID := c.Query("room_id"); roomID != "" {
+		var room models.Room
+		if err := database.DB.First(&room, roomID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+			return
+		}
+		if err := policy.CanManageRoom(member, room.ID); err != nil {
+			middleware.AbortWithPolicyError(c, err)
+			return
+		}
+		db = db.Where("room_id = ?", room.ID)
+	} else {
+		db = db.Where("member_id = ?", member.ID)
+	}
+	if date := c.Query("date"); date != "" {
+		db = db.Where("date = ?", date)
+	}
+	if status := c.Query("status"); status != "" {
+		db = db.Where("status = ?", status)
+	} else {
+		db = db.Where("status IN ?", []string{"waiting", "offered"})
+	}
+
+	var entries []models.WaitlistEntry
+	if err := db.Preload("Room").Preload("Member").Preload("Users").Order("date asc, id asc").Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist"})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// 退出候补；退出已收到认领通知的候补时，时间段会提供给下一位候补会员
+func WithdrawWaitlist(c *gin.Context) {
+	entry, ok := ownWaitlistEntry(c)
+	if !ok {
+		return
+	}
+	if entry.Status != "waiting" && entry.Status != "offered" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only waiting or offered entries can be withdrawn"})
+		return
+	}
+
+	wasOffered := entry.Status == "offered"
+	if err := database.DB.Model(entry).Updates(map[string]interface{}{"status": "withdrawn", "offer_expires_at": nil}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to withdraw from waitlist"})
+		return
+	}
+	if wasOffered {
+		go processWaitlist(middleware.Token(c), entry.RoomID, entry.Date)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Withdrawn from waitlist successfully"})
+}
+
+// 认领候补提供的时间段，认领后立即创建预定
+func ClaimWaitlist(c *gin.Context) {
+	entry, ok := ownWaitlistEntry(c)
+	if !ok {
+		return
+	}
+	if entry.Status != "offered" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No time slots have been offered for this entry"})
+		return
+	}
+	if entry.OfferExpiresAt == nil || time.Now().After(*entry.OfferExpiresAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The offer has expired"})
+		return
+	}
+
+	booking, err := bookWaitlistEntry(middleware.Token(c), entry, false)
+	var exceeded *quotaExceededError
+	if errors.As(err, &exceeded) {
+		c.JSON(http.StatusBadRequest, quotaError(exceeded.policy, exceeded.violation))
+		return
+	}
+	if errors.Is(err, errSlotsTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Some time slots are already booked"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to claim time slots"})
+		return
+	}
+
+	go outbox.Deliver()
+	go webhook.Deliver()
+	c.JSON(http.StatusCreated, booking)
+}
+
+// 定时任务：过期已开始的候补和超过认领时限的通知，超时未认领的时间段提供给下一位候补会员
+func ExpireWaitlistEntries() {
+	now := time.Now()
+
+	// 会议已开始的候补不再有意义
+	var started []models.WaitlistEntry
+	database.DB.Where("status IN ? AND date <= ?", []string{"waiting", "offered"}, now.Format("2006-01-02")).Find(&started)
+	for _, entry := range started {
+		if now.Before(bookingClock(entry.Date, entry.StartTime)) {
+			continue
+		}
+		database.DB.Model(&entry).Updates(map[string]interface{}{"status": "expired", "offer_expires_at": nil})
+	}
+
+	// 超过认领时限的通知
+	var lapsed []models.WaitlistEntry
+	database.DB.Where("status = ? AND offer_expires_at <= ?", "offered", now).Find(&lapsed)
+	for _, entry := range lapsed {
+		database.DB.Model(&entry).Updates(map[string]interface{}{"status": "expired", "offer_expires_at": nil})
+		processWaitlist(models.SystemToken(), entry.RoomID, entry.Date)
+	}
+}
+
+// 时间段被释放后处理该会议室当天的候补：按加入顺序，所需时间段已空闲的候补自动预定或收到认领通知
+func processWaitlist(token string, roomID uint, date string) {
+	var entries []models.WaitlistEntry
+	database.DB.Preload("Room").Preload("Member").Preload("Users").
+		Where("room_id = ? AND date = ? AND status = ?", roomID, date, "waiting").
+		Order("id asc").Find(&entries)
+
+	now := time.Now()
+	notified := false
+	for i := range entries {
+		entry := &entries[i]
+		window := timeslot.MustWindow(entry.StartTime, entry.EndTime)
+		if !now.Before(bookingClock(entry.Date, entry.StartTime)) {
+			continue
+		}
+		if !areSlotsAvailable(roomID, date, window) || findWaitlistHold(database.DB, roomID, date, window, 0) != nil {
+			continue
+		}
+		if findBlackout(database.DB, roomID, date, window) != nil {
+			continue
+		}
+
+		if entry.AutoBook {
+			if _, err := bookWaitlistEntry(token, entry, true); err == nil {
+				notified = true
+			}
+			continue
+		}
+
+		expiresAt := now.Add(waitlistClaimWindow)
+		var offered bool
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&models.WaitlistEntry{}).
+				Where("id = ? AND status = ?", entry.ID, "waiting").
+				Updates(map[string]interface{}{"status": "offered", "offer_expires_at": expiresAt})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			offered = true
+			return notifyWaitlistEntry(tx, token, entry, "waitlist_offer", expiresAt.Format("2006-01-02 15:04"))
+		})
+		if err == nil && offered {
+			notified = true
+		}
+	}
+	if notified {
+		go outbox.Deliver()
+		go webhook.Deliver()
+	}
+}
+
+// 为候补创建预定，并将候补标记为已预定；预定计入候补会员的配额，需要审批的会议室中预定仍需等待审批。
+// 预定通知在同一事务中写入，autoBooked 时还通知候补会员已自动预定
+func bookWaitlistEntry(token string, entry *models.WaitlistEntry, autoBooked bool) (*models.Booking, error) {
+	var member models.Member
+	if err := database.DB.Preload("ManagedRooms").First(&member, entry.MemberID).Error; err != nil {
+		return nil, err
+	}
+	booking := models.Booking{
+		RoomID:    entry.RoomID,
+		MemberID:  entry.MemberID,
+		Date:      entry.Date,
+		StartTime: entry.StartTime,
+		EndTime:   entry.EndTime,
+		Reason:    entry.Reason,
+		Status:    bookingStatus(&entry.Room, &member),
+	}
+	var users []models.BookingUser
+	for _, user := range entry.Users {
+		users = append(users, models.BookingUser{Userid: user.Userid, Nickname: user.Nickname})
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := enforceQuota(tx, &entry.Room, &member, entry.Date, timeslot.MustWindow(entry.StartTime, entry.EndTime), 0); err != nil {
+			return err
+		}
+		if err := insertBooking(tx, &booking, users); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.WaitlistEntry{}).Where("id = ?", entry.ID).
+			Updates(map[string]interface{}{"status": "booked", "booking_id": booking.ID, "offer_expires_at": nil}).Error; err != nil {
+			return err
+		}
+		booking.Room = entry.Room
+		if err := notifyWaitlistBooking(tx, token, &booking); err != nil {
+			return err
+		}
+		if autoBooked {
+			return notifyWaitlistEntry(tx, token, entry, "waitlist_booked", "")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	database.DB.Preload("Room").Preload("Member").Preload("BookingUsers").First(&booking, booking.ID)
+	return &booking, nil
+}
+
+// 获取会议室当天正在等待候补会员认领的时间段，exceptMemberID 的候补除外
+func waitlistHolds(db *gorm.DB, roomID uint, date string, exceptMemberID uint) []models.WaitlistEntry {
+	var offers []models.WaitlistEntry
+	db.Where("room_id = ? AND date = ? AND status = ? AND offer_expires_at > ? AND member_id <> ?", roomID, date, "offered", time.Now(), exceptMemberID).Find(&offers)
+	return offers
+}
+
+// 查找与指定时间区间重叠、正在等待其他会员认领的候补
+func findWaitlistHold(db *gorm.DB, roomID uint, date string, window timeslot.Window, exceptMemberID uint) *models.WaitlistEntry {
+	offers := waitlistHolds(db, roomID, date, exceptMemberID)
+	for i := range offers {
+		if timeslot.MustWindow(offers[i].StartTime, offers[i].EndTime).Overlaps(window) {
+			return &offers[i]
+		}
+	}
+	return nil
+}
+
+// 标记正在等待其他候补会员认领的时间段
+func markHeldSlots(slots []models.TimeSlot, offers []models.WaitlistEntry) []models.TimeSlot {
+	for i := range slots {
+		window := timeslot.MustWindow(slots[i].Start, slots[i].End)
+		for _, offer := range offers {
+			if timeslot.MustWindow(offer.StartTime, offer.EndTime).Overlaps(window) {
+				slots[i].IsBooked = true
+				break
+			}
+		}
+	}
+	return slots
+}
+
+// 候补时间段被占用时的错误信息
+func waitlistHoldError(hold *models.WaitlistEntry) error {
+	return fmt.Errorf("Time slots are held for a waitlisted member until %s", hold.OfferExpiresAt.Format("15:04"))
+}
+
+// 加载路径中属于当前会员的候补
+func ownWaitlistEntry(c *gin.Context) (*models.WaitlistEntry, bool) {
+	var entry models.WaitlistEntry
+	if err := database.DB.Preload("Room").Preload("Users").First(&entry, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Waitlist entry not found"})
+		return nil, false
+	}
+	if entry.MemberID != middleware.CurrentMember(c).ID {
+		middleware.AbortWithPolicyError(c, policy.ErrForbidden)
+		return nil, false
+	}
+	return &entry, true
+}
+
+// 在事务中写入候补成功的通知，通知参会人员和会议室管理员，待审批的预定只通知会议室管理员审批
+func notifyWaitlistBooking(tx *gorm.DB, token string, booking *models.Booking) error {
+	if token == "" {
+		return nil
+	}
+	if booking.Status == "pending" {
+		return notifyApprovalRequest(tx, token, booking)
+	}
+	var userIDs []int
+	for _, user := range booking.BookingUsers {
+		userIDs = append(userIDs, int(user.Userid))
+	}
+	calendarURL, err := bookingCalendarURL(tx, booking)
+	if err != nil {
+		return err
+	}
+	return outbox.Publish(tx, notify.Event{
+		Type:        "remind",
+		UserIDs:     userIDs,
+		AdminIDs:    getRoomAdminIDs(booking.RoomID),
+		Token:       token,
+		Date:        booking.Date,
+		StartTime:   booking.StartTime,
+		EndTime:     booking.EndTime,
+		RoomName:    booking.Room.Name,
+		Reason:      booking.Reason,
+		Attendees:   joinNicknames(booking.BookingUsers),
+		CalendarURL: calendarURL,
+	})
+}
+
+// 在事务中写入通知，告知候补会员自动预定成功或时间段可以认领
+func notifyWaitlistEntry(tx *gorm.DB, token string, entry *models.WaitlistEntry, msgType string, deadline string) error {
+	if token == "" {
+		fmt.Printf("未配置 DOOTASK_BOT_TOKEN，跳过候补通知: 候补%d\n", entry.ID)
+		return nil
+	}
+	var attendees []models.BookingUser
+	for _, user := range entry.Users {
+		attendees = append(attendees, models.BookingUser{Userid: user.Userid, Nickname: user.Nickname})
+	}
+	return outbox.Publish(tx, notify.Event{
+		Type:         msgType,
+		OrganizerIDs: []int{int(entry.Member.DootaskID)},
+		Token:        token,
+		Date:         entry.Date,
+		StartTime:    entry.StartTime,
+		EndTime:      entry.EndTime,
+		RoomName:     entry.Room.Name,
+		Reason:       entry.Reason,
+		Attendees:    joinNicknames(attendees),
+		Content:      []string{deadline},
+	})
+}
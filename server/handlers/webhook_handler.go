@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"roomly/database"
+	"roomly/middleware"
+	"roomly/models"
+	"roomly/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 回调订阅请求结构，events 为空表示订阅全部事件，secret 为空时自动生成，is_active 默认启用
+type webhookRequest struct {
+	Name     string   `json:"name" binding:"required"`
+	URL      string   `json:"url" binding:"required"`
+	Events   []string `json:"events"`
+	Secret   string   `json:"secret"`
+	IsActive *bool    `json:"is_active"`
+}
+
+// 获取所有回调订阅
+func GetWebhooks(c *gin.Context) {
+	var subscriptions []models.WebhookSubscription
+	if err := database.DB.Order("id asc").Find(&subscriptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+		return
+	}
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// 获取可以订阅的预定事件
+func GetWebhookEvents(c *gin.Context) {
+	c.JSON(http.StatusOK, webhook.Events)
+}
+
+// 创建回调订阅
+func CreateWebhook(c *gin.Context) {
+	var request webhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	subscription := models.WebhookSubscription{IsActive: true, CreatedBy: middleware.CurrentMember(c).ID}
+	if err := applyWebhookRequest(&subscription, &request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if subscription.Secret == "" {
+		subscription.Secret = webhook.NewSecret()
+	}
+
+	if err := database.DB.Create(&subscription).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+	c.JSON(http.StatusCreated, subscription)
+}
+
+// 更新回调订阅，secret 为空时保留原密钥
+func UpdateWebhook(c *gin.Context) {
+	var subscription models.WebhookSubscription
+	if err := database.DB.First(&subscription, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	var request webhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applyWebhookRequest(&subscription, &request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Save(&subscription).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update webhook"})
+		return
+	}
+	c.JSON(http.StatusOK, subscription)
+}
+
+// 删除回调订阅及其投递记录
+func DeleteWebhook(c *gin.Context) {
+	var subscription models.WebhookSubscription
+	if err := database.DB.First(&subscription, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	if err := database.DB.Where("subscription_id = ?", subscription.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+	if err := database.DB.Delete(&subscription).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// 发送测试事件，同步返回本次投递的结果
+func TestWebhook(c *gin.Context) {
+	var subscription models.WebhookSubscription
+	if err := database.DB.First(&subscription, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	delivery, err := webhook.Ping(&subscription)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send test event"})
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+// 获取回调订阅的投递记录，可按状态（pending、sent、dead）和事件筛选
+func GetWebhookDeliveries(c *gin.Context) {
+	// 解析分页参数
+	pageStr := c.DefaultQuery("page", "1")
+	pageSizeStr := c.DefaultQuery("page_size", "20")
+	page, err := strconv.Atoi(pageStr)
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(pageSizeStr)
+	if err != nil || pageSize < 1 {
+		pageSize = 20
+	}
+
+	db := database.DB.Model(&models.WebhookDelivery{}).Where("subscription_id = ?", c.Param("id"))
+	if status := c.Query("status"); status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if event := c.Query("event"); event != "" {
+		db = db.Where("event = ?", event)
+	}
+
+	var total int64
+	db.Count(&total)
+
+	var deliveries []models.WebhookDelivery
+	if err := db.Order("id desc").Limit(pageSize).Offset((page - 1) * pageSize).Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook deliveries"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":  deliveries,
+		"total": total,
+	})
+}
+
+// 重新投递单条未成功的投递记录
+func RetryWebhookDelivery(c *gin.Context) {
+	var delivery models.WebhookDelivery
+	if err := database.DB.Where("subscription_id = ?", c.Param("id")).First(&delivery, c.Param("delivery_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook delivery not found"})
+		return
+	}
+	if err := webhook.Retry(database.DB, &delivery); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	go webhook.Deliver()
+	c.JSON(http.StatusOK, delivery)
+}
+
+// 校验回调订阅请求并写入订阅：URL 必须是 http 或 https 地址，事件必须是可以订阅的预定事件
+func applyWebhookRequest(subscription *models.WebhookSubscription, request *webhookRequest) error {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return errors.New("name is required")
+	}
+	rawURL := strings.TrimSpace(request.URL)
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("url must be an http or https URL")
+	}
+
+	var events []string
+	seen := make(map[string]bool)
+	for _, event := range request.Events {
+		event = strings.TrimSpace(event)
+		if event == "" || seen[event] {
+			continue
+		}
+		if !webhook.IsEvent(event) {
+			return errors.New("Unknown event " + event)
+		}
+		seen[event] = true
+		events = append(events, event)
+	}
+
+	subscription.Name = name
+	subscription.URL = rawURL
+	subscription.Events = strings.Join(events, ",")
+	if secret := strings.TrimSpace(request.Secret); secret != "" {
+		subscription.Secret = secret
+	}
+	if request.IsActive != nil {
+		subscription.IsActive = *request.IsActive
+	}
+	return nil
+}
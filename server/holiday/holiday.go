@@ -0,0 +1,216 @@
+// Package holiday 解析节假日日历文件（ICS、CSV），生成按天的节假日和调休工作日记录。
+// 周六、周日默认为非工作日，标记为调休工作日（补班）的周末视为工作日。
+package holiday
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// 日历记录类型
+const (
+	// Holiday 节假日，不上班
+	Holiday = "holiday"
+	// Workday 调休工作日（补班），通常在周末
+	Workday = "workday"
+)
+
+// 识别为调休工作日的事件名称关键字
+var workdayKeywords = []string{"补班", "上班", "workday", "working day"}
+
+// Entry 某一天的日历记录
+type Entry struct {
+	Date string // 格式: YYYY-MM-DD
+	Kind string // Holiday 或 Workday
+	Name string
+}
+
+// IsValidKind 判断记录类型是否合法
+func IsValidKind(kind string) bool {
+	return kind == Holiday || kind == Workday
+}
+
+// IsWorkingDay 判断指定日期是否为工作日；entry 为当天的日历记录，没有时为 nil
+func IsWorkingDay(date time.Time, entry *Entry) bool {
+	if entry != nil {
+		return entry.Kind == Workday
+	}
+	return date.Weekday() != time.Saturday && date.Weekday() != time.Sunday
+}
+
+// ParseICS 解析 ICS 文件中的 VEVENT，多天事件按天展开（DTEND 为不包含的结束日期）；
+// 名称包含“补班”“上班”等关键字的事件视为调休工作日，其余视为节假日
+func ParseICS(r io.Reader) ([]Entry, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	var inEvent bool
+	var start, end, summary string
+	for _, line := range lines {
+		switch {
+		case line == "BEGIN:VEVENT":
+			inEvent = true
+			start, end, summary = "", "", ""
+		case line == "END:VEVENT":
+			inEvent = false
+			if start == "" {
+				continue
+			}
+			days, err := expandDays(start, end)
+			if err != nil {
+				return nil, err
+			}
+			kind := Holiday
+			if isWorkdayName(summary) {
+				kind = Workday
+			}
+			for _, day := range days {
+				entries = append(entries, Entry{Date: day, Kind: kind, Name: summary})
+			}
+		case inEvent:
+			name, value, ok := splitProperty(line)
+			if !ok {
+				continue
+			}
+			switch name {
+			case "DTSTART":
+				start = value
+			case "DTEND":
+				end = value
+			case "SUMMARY":
+				summary = unescapeText(value)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// ParseCSV 解析 CSV 文件，列依次为 date,kind,name；kind 可省略（默认为节假日），
+// 第一行不是日期时视为表头跳过
+func ParseCSV(r io.Reader) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for i, record := range records {
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		date := strings.TrimSpace(strings.TrimPrefix(record[0], "\ufeff"))
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			if i == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: invalid date %q, use YYYY-MM-DD", i+1, date)
+		}
+		entry := Entry{Date: date, Kind: Holiday}
+		if len(record) > 1 && strings.TrimSpace(record[1]) != "" {
+			entry.Kind = strings.ToLower(strings.TrimSpace(record[1]))
+			if !IsValidKind(entry.Kind) {
+				return nil, fmt.Errorf("line %d: kind must be holiday or workday", i+1)
+			}
+		}
+		if len(record) > 2 {
+			entry.Name = strings.TrimSpace(record[2])
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// 合并 ICS 的折行（以空格或制表符开头的行是上一行的延续）
+func unfoldLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// 拆分 ICS 属性行，返回去掉参数的属性名和值，如 DTSTART;VALUE=DATE:20260101
+func splitProperty(line string) (string, string, bool) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return "", "", false
+	}
+	name := line[:colon]
+	if semicolon := strings.Index(name, ";"); semicolon >= 0 {
+		name = name[:semicolon]
+	}
+	return strings.ToUpper(name), line[colon+1:], true
+}
+
+// 将 DTSTART/DTEND 展开为日期列表；全天事件的 DTEND 不包含在内
+func expandDays(start, end string) ([]string, error) {
+	from, err := parseICSDate(start)
+	if err != nil {
+		return nil, err
+	}
+	to := from.AddDate(0, 0, 1)
+	if end != "" {
+		if to, err = parseICSDate(end); err != nil {
+			return nil, err
+		}
+		// 带时间的事件结束于当天（非零点）时包含结束日期
+		if len(end) > 8 && !strings.HasPrefix(end[8:], "T000000") {
+			to = to.AddDate(0, 0, 1)
+		}
+	}
+
+	var days []string
+	for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+		days = append(days, d.Format("2006-01-02"))
+	}
+	if len(days) == 0 {
+		days = append(days, from.Format("2006-01-02"))
+	}
+	return days, nil
+}
+
+// 解析 ICS 日期，只取日期部分（YYYYMMDD）
+func parseICSDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, errors.New("invalid ICS date: " + value)
+	}
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, errors.New("invalid ICS date: " + value)
+	}
+	return date, nil
+}
+
+// 还原 ICS 文本中的转义字符
+func unescapeText(value string) string {
+	replacer := strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return strings.TrimSpace(replacer.Replace(value))
+}
+
+// 判断事件名称是否表示调休工作日
+func isWorkdayName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, keyword := range workdayKeywords {
+		if strings.Contains(lower, keyword) {
+			return true
+		}
+	}
+	return false
+}
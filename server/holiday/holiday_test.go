@@ -0,0 +1,220 @@
+package holiday
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 节假日订阅源导出的日历：CRLF 换行、折行、带参数的全天事件、转义字符和带时间的事件
+const feed = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Apple Inc.//macOS 14.0//EN\r\n" +
+	"CALSCALE:GREGORIAN\r\n" +
+	"X-WR-CALNAME:中国节假日\r\n" +
+	"X-WR-TIMEZONE:Asia/Shanghai\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:20250126_work@holiday\r\n" +
+	"DTSTAMP:20241112T080000Z\r\n" +
+	"DTSTART;VALUE=DATE:20250126\r\n" +
+	"DTEND;VALUE=DATE:20250127\r\n" +
+	"SUMMARY:春节补班\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:20250128_holiday@holiday\r\n" +
+	"DTSTAMP:20241112T080000Z\r\n" +
+	"DTSTART;VALUE=DATE:20250128\r\n" +
+	"DTEND;VALUE=DATE:20250205\r\n" +
+	"SUMMARY:春节\\, 放假8天\r\n" +
+	"DESCRIPTION:1月28日至2月4日放假调休\\n共8天\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:20250208_work@holiday\r\n" +
+	"DTSTART;VALUE=DATE:20250208\r\n" +
+	"SUMMARY:春节调休\r\n" +
+	"  上班\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:20251001_holiday@holiday\r\n" +
+	"DTSTART;TZID=Asia/Shanghai:20251001T000000\r\n" +
+	"DTEND;TZID=Asia/Shanghai:20251003T000000\r\n" +
+	"SUMMARY:国庆节\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:20251011_work@holiday\r\n" +
+	"DTSTART:20251011T090000\r\n" +
+	"DTEND:20251011T180000\r\n" +
+	"SUMMARY:Working Day\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	entries, err := ParseICS(strings.NewReader(feed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{Date: "2025-01-26", Kind: Workday, Name: "春节补班"},
+		{Date: "2025-01-28", Kind: Holiday, Name: "春节, 放假8天"},
+		{Date: "2025-01-29", Kind: Holiday, Name: "春节, 放假8天"},
+		{Date: "2025-01-30", Kind: Holiday, Name: "春节, 放假8天"},
+		{Date: "2025-01-31", Kind: Holiday, Name: "春节, 放假8天"},
+		{Date: "2025-02-01", Kind: Holiday, Name: "春节, 放假8天"},
+		{Date: "2025-02-02", Kind: Holiday, Name: "春节, 放假8天"},
+		{Date: "2025-02-03", Kind: Holiday, Name: "春节, 放假8天"},
+		{Date: "2025-02-04", Kind: Holiday, Name: "春节, 放假8天"},
+		{Date: "2025-02-08", Kind: Workday, Name: "春节调休 上班"},
+		{Date: "2025-10-01", Kind: Holiday, Name: "国庆节"},
+		{Date: "2025-10-02", Kind: Holiday, Name: "国庆节"},
+		{Date: "2025-10-11", Kind: Workday, Name: "Working Day"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("ParseICS =\n%+v\nwant\n%+v", entries, want)
+	}
+}
+
+func TestParseICSExpandsDays(t *testing.T) {
+	tests := []struct {
+		name  string
+		start string
+		end   string
+		want  []string
+	}{
+		{"without DTEND", "DTSTART;VALUE=DATE:20251001", "", []string{"2025-10-01"}},
+		{"exclusive DTEND", "DTSTART;VALUE=DATE:20251001", "DTEND;VALUE=DATE:20251002", []string{"2025-10-01"}},
+		{"multi-day DTEND", "DTSTART;VALUE=DATE:20251001", "DTEND;VALUE=DATE:20251009",
+			[]string{"2025-10-01", "2025-10-02", "2025-10-03", "2025-10-04", "2025-10-05", "2025-10-06", "2025-10-07", "2025-10-08"}},
+		{"across months", "DTSTART;VALUE=DATE:20250131", "DTEND;VALUE=DATE:20250203", []string{"2025-01-31", "2025-02-01", "2025-02-02"}},
+		{"DTEND before DTSTART", "DTSTART;VALUE=DATE:20251001", "DTEND;VALUE=DATE:20250930", []string{"2025-10-01"}},
+		{"timed DTEND at midnight", "DTSTART:20251001T000000Z", "DTEND:20251003T000000Z", []string{"2025-10-01", "2025-10-02"}},
+		{"timed DTEND during the day", "DTSTART:20251001T090000Z", "DTEND:20251003T120000Z", []string{"2025-10-01", "2025-10-02", "2025-10-03"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := []string{"BEGIN:VCALENDAR", "BEGIN:VEVENT", tt.start}
+			if tt.end != "" {
+				lines = append(lines, tt.end)
+			}
+			lines = append(lines, "SUMMARY:国庆节", "END:VEVENT", "END:VCALENDAR")
+			entries, err := ParseICS(strings.NewReader(strings.Join(lines, "\n")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var days []string
+			for _, entry := range entries {
+				days = append(days, entry.Date)
+			}
+			if !reflect.DeepEqual(days, tt.want) {
+				t.Errorf("days = %v, want %v", days, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseICSWorkdayKeywords(t *testing.T) {
+	tests := []struct {
+		summary string
+		want    string
+	}{
+		{"劳动节补班", Workday},
+		{"调休上班", Workday},
+		{"Workday (Spring Festival)", Workday},
+		{"Working Day", Workday},
+		{"劳动节", Holiday},
+		{"Labour Day", Holiday},
+	}
+	for _, tt := range tests {
+		t.Run(tt.summary, func(t *testing.T) {
+			ics := "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20250427\nSUMMARY:" + tt.summary + "\nEND:VEVENT\n"
+			entries, err := ParseICS(strings.NewReader(ics))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 || entries[0].Kind != tt.want {
+				t.Errorf("entries = %+v, want kind %s", entries, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseICSRejectsInvalidDate(t *testing.T) {
+	for _, start := range []string{"DTSTART:2025", "DTSTART:2025-10-01"} {
+		ics := "BEGIN:VEVENT\n" + start + "\nSUMMARY:国庆节\nEND:VEVENT\n"
+		if _, err := ParseICS(strings.NewReader(ics)); err == nil {
+			t.Errorf("ParseICS with %q succeeded", start)
+		}
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want []Entry
+	}{
+		{
+			// Excel 导出的文件：带 BOM、表头和 CRLF
+			name: "exported from Excel",
+			csv:  "\ufeffdate,kind,name\r\n2025-01-26,workday,春节补班\r\n2025-01-28,holiday,春节\r\n",
+			want: []Entry{
+				{Date: "2025-01-26", Kind: Workday, Name: "春节补班"},
+				{Date: "2025-01-28", Kind: Holiday, Name: "春节"},
+			},
+		},
+		{
+			name: "without header",
+			csv:  "2025-10-01,holiday,国庆节\n2025-10-11,workday,国庆节补班\n",
+			want: []Entry{
+				{Date: "2025-10-01", Kind: Holiday, Name: "国庆节"},
+				{Date: "2025-10-11", Kind: Workday, Name: "国庆节补班"},
+			},
+		},
+		{
+			name: "kind omitted",
+			csv:  "date,kind,name\n2025-10-01,,国庆节\n2025-10-02\n",
+			want: []Entry{
+				{Date: "2025-10-01", Kind: Holiday, Name: "国庆节"},
+				{Date: "2025-10-02", Kind: Holiday},
+			},
+		},
+		{
+			name: "spaces, case and blank lines",
+			csv:  "2025-09-28, Workday , 国庆节补班 \n\n, ,\n2025-10-01, HOLIDAY, \"国庆节, 中秋节\"\n",
+			want: []Entry{
+				{Date: "2025-09-28", Kind: Workday, Name: "国庆节补班"},
+				{Date: "2025-10-01", Kind: Holiday, Name: "国庆节, 中秋节"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := ParseCSV(strings.NewReader(tt.csv))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(entries, tt.want) {
+				t.Errorf("ParseCSV =\n%+v\nwant\n%+v", entries, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCSVRejectsInvalidRecords(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want string
+	}{
+		{"invalid date", "date,kind,name\n2025/10/01,holiday,国庆节\n", "line 2: invalid date"},
+		{"invalid kind", "2025-10-01,vacation,国庆节\n", "line 1: kind must be holiday or workday"},
+		{"unterminated quote", "2025-10-01,holiday,\"国庆节\n", "extraneous or missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCSV(strings.NewReader(tt.csv))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseCSV error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
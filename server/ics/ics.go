@@ -0,0 +1,264 @@
+// Package ics 生成 iCalendar（RFC 5545）日历文件，用于下载单个预定和日历客户端订阅。
+// 设置了日历时区时，事件的开始、结束时间带 TZID 按该时区的本地时间输出并附带 VTIMEZONE，
+// 周期事件在夏令时切换前后保持同一本地时间；其余时间戳和 RRULE 的 UNTIL 以 UTC 输出。
+package ics
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 事件状态
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusTentative = "TENTATIVE"
+	StatusCancelled = "CANCELLED"
+)
+
+// 产品标识
+const prodID = "-//Roomly//Roomly//CN"
+
+// 每行最多的字节数，超过时折行
+const maxLineOctets = 75
+
+// Event 日历事件
+type Event struct {
+	UID          string
+	RecurrenceID *time.Time // 覆盖周期事件中某一次发生时为该次发生原来的开始时间
+	Summary      string
+	Description  string
+	Location     string
+	Start        time.Time
+	End          time.Time
+	Status       string      // CONFIRMED、TENTATIVE 或 CANCELLED
+	RRule        string      // 周期规则，如 FREQ=WEEKLY;BYDAY=MO，非周期事件为空
+	ExDates      []time.Time // 周期规则中不发生的开始时间
+	Created      time.Time
+	LastModified time.Time
+}
+
+// Calendar 日历
+type Calendar struct {
+	Name     string         // 日历名称，订阅时显示
+	Refresh  time.Duration  // 建议的订阅刷新间隔，0 表示不设置
+	Location *time.Location // 事件时间所在的时区，为空时以 UTC 输出
+	Events   []Event
+}
+
+// Encode 生成日历文件内容
+func (c *Calendar) Encode() []byte {
+	var b strings.Builder
+	write := func(line string) {
+		b.WriteString(fold(line))
+		b.WriteString("\r\n")
+	}
+
+	write("BEGIN:VCALENDAR")
+	write("VERSION:2.0")
+	write("PRODID:" + prodID)
+	write("CALSCALE:GREGORIAN")
+	write("METHOD:PUBLISH")
+	if c.Name != "" {
+		write("X-WR-CALNAME:" + escape(c.Name))
+	}
+	if c.Refresh > 0 {
+		duration := fmt.Sprintf("PT%dM", int(c.Refresh.Minutes()))
+		write("REFRESH-INTERVAL;VALUE=DURATION:" + duration)
+		write("X-PUBLISHED-TTL:" + duration)
+	}
+	if c.Location != nil {
+		for _, line := range c.timezone() {
+			write(line)
+		}
+	}
+	for _, event := range c.Events {
+		stamp := event.LastModified
+		if stamp.IsZero() {
+			stamp = time.Now()
+		}
+		write("BEGIN:VEVENT")
+		write("UID:" + event.UID)
+		write("DTSTAMP:" + FormatTime(stamp))
+		if event.RecurrenceID != nil {
+			write(c.dateTime("RECURRENCE-ID", *event.RecurrenceID))
+		}
+		write(c.dateTime("DTSTART", event.Start))
+		write(c.dateTime("DTEND", event.End))
+		if event.RRule != "" {
+			write("RRULE:" + event.RRule)
+		}
+		for _, exDate := range event.ExDates {
+			write(c.dateTime("EXDATE", exDate))
+		}
+		write("SUMMARY:" + escape(event.Summary))
+		if event.Description != "" {
+			write("DESCRIPTION:" + escape(event.Description))
+		}
+		if event.Location != "" {
+			write("LOCATION:" + escape(event.Location))
+		}
+		if event.Status != "" {
+			write("STATUS:" + event.Status)
+		}
+		if !event.Created.IsZero() {
+			write("CREATED:" + FormatTime(event.Created))
+		}
+		if !event.LastModified.IsZero() {
+			write("LAST-MODIFIED:" + FormatTime(event.LastModified))
+		}
+		write("END:VEVENT")
+	}
+	write("END:VCALENDAR")
+	return []byte(b.String())
+}
+
+// FormatTime 以 UTC 格式输出时间，如 20250106T020000Z，也用于 RRULE 的 UNTIL
+func FormatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// LocalLocation 服务器的本地时区。time.Local 的名称固定为 Local，
+// 因此优先按 TZ 环境变量或 /etc/localtime 链接解析出 IANA 名称（如 Asia/Shanghai）作为 TZID
+func LocalLocation() *time.Location {
+	name := strings.TrimPrefix(os.Getenv("TZ"), ":")
+	if name == "" {
+		if target, err := os.Readlink("/etc/localtime"); err == nil {
+			if i := strings.Index(target, "zoneinfo/"); i >= 0 {
+				name = target[i+len("zoneinfo/"):]
+			}
+		}
+	}
+	if name != "" {
+		if location, err := time.LoadLocation(name); err == nil {
+			return location
+		}
+	}
+	return time.Local
+}
+
+// 输出日期时间属性：设置了时区时带 TZID 输出本地时间，否则输出 UTC
+func (c *Calendar) dateTime(name string, t time.Time) string {
+	if c.Location == nil {
+		return name + ":" + FormatTime(t)
+	}
+	return name + ";TZID=" + c.Location.String() + ":" + t.In(c.Location).Format("20060102T150405")
+}
+
+// 日历时区的 VTIMEZONE：以最早的事件所在年份开始时的偏移为起点，
+// 加上到最晚的事件之后两年内的所有时区偏移变化
+func (c *Calendar) timezone() []string {
+	first, last := time.Now(), time.Now()
+	if len(c.Events) > 0 {
+		first, last = c.Events[0].Start, c.Events[0].End
+	}
+	for _, event := range c.Events {
+		if event.Start.Before(first) {
+			first = event.Start
+		}
+		if event.End.After(last) {
+			last = event.End
+		}
+	}
+	from := time.Date(first.In(c.Location).Year(), time.January, 1, 0, 0, 0, 0, c.Location)
+	to := time.Date(last.In(c.Location).Year()+2, time.January, 1, 0, 0, 0, 0, c.Location)
+
+	lines := []string{"BEGIN:VTIMEZONE", "TZID:" + c.Location.String()}
+	name, offset := from.Zone()
+	changes := []transition{{at: from, name: name, offsetFrom: offset, offsetTo: offset, daylight: from.IsDST()}}
+	for _, change := range append(changes, transitions(c.Location, from, to)...) {
+		kind := "STANDARD"
+		if change.daylight {
+			kind = "DAYLIGHT"
+		}
+		lines = append(lines,
+			"BEGIN:"+kind,
+			// DTSTART 为变化前的本地时间
+			"DTSTART:"+change.at.UTC().Add(time.Duration(change.offsetFrom)*time.Second).Format("20060102T150405"),
+			"TZOFFSETFROM:"+formatOffset(change.offsetFrom),
+			"TZOFFSETTO:"+formatOffset(change.offsetTo),
+			"TZNAME:"+change.name,
+			"END:"+kind,
+		)
+	}
+	return append(lines, "END:VTIMEZONE")
+}
+
+// 时区偏移的一次变化
+type transition struct {
+	at         time.Time // 变化的时刻
+	name       string    // 变化后的时区缩写
+	offsetFrom int       // 变化前相对 UTC 的秒数
+	offsetTo   int       // 变化后相对 UTC 的秒数
+	daylight   bool      // 变化后是否为夏令时
+}
+
+// 查找时区在 [from, to) 内的偏移变化：逐天比较偏移，发现变化后二分查找到秒
+func transitions(location *time.Location, from, to time.Time) []transition {
+	var changes []transition
+	_, previous := from.In(location).Zone()
+	for day := from; day.Before(to); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		if _, offset := next.In(location).Zone(); offset == previous {
+			continue
+		}
+		low, high := day, next
+		for high.Sub(low) > time.Second {
+			middle := low.Add(high.Sub(low) / 2)
+			if _, offset := middle.In(location).Zone(); offset == previous {
+				low = middle
+			} else {
+				high = middle
+			}
+		}
+		// 偏移变化发生在整秒
+		at := high.Truncate(time.Second).In(location)
+		name, offset := at.Zone()
+		changes = append(changes, transition{at: at, name: name, offsetFrom: previous, offsetTo: offset, daylight: at.IsDST()})
+		previous = offset
+	}
+	return changes
+}
+
+// 时区偏移的格式，如 +0800、-0330
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+}
+
+// 转义文本属性值中的反斜杠、分号、逗号和换行
+func escape(text string) string {
+	text = strings.ReplaceAll(text, "\\", "\\\\")
+	text = strings.ReplaceAll(text, ";", "\\;")
+	text = strings.ReplaceAll(text, ",", "\\,")
+	text = strings.ReplaceAll(text, "\r\n", "\\n")
+	return strings.ReplaceAll(text, "\n", "\\n")
+}
+
+// 按 75 字节折行，续行以空格开头，不拆开多字节字符
+func fold(line string) string {
+	if len(line) <= maxLineOctets {
+		return line
+	}
+	var b strings.Builder
+	limit := maxLineOctets
+	width := 0
+	for _, r := range line {
+		size := utf8.RuneLen(r)
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			// 续行开头的空格占一个字节
+			limit = maxLineOctets - 1
+			width = 0
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
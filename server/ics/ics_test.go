@@ -0,0 +1,105 @@
+package ics
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// 与 testdata 中的文件比较，-update 时改为写入
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s mismatch\ngot:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+// 跨越夏令时切换的周期会议，带一次改期、一次不发生的日期和一个已取消的单次会议
+func sampleEvents(location *time.Location) []Event {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, time.March, day, hour, minute, 0, 0, location)
+	}
+	created := time.Date(2025, time.March, 1, 8, 0, 0, 0, time.UTC)
+	modified := time.Date(2025, time.March, 2, 9, 30, 0, 0, time.UTC)
+	moved := at(31, 10, 0)
+	return []Event{
+		{
+			UID:          "series-1@roomly",
+			Summary:      "项目周会",
+			Description:  "预定人：张三\n参会人员：张三、李四",
+			Location:     "多功能会议室A",
+			Start:        at(24, 10, 0),
+			End:          at(24, 11, 0),
+			Status:       StatusConfirmed,
+			RRule:        "FREQ=WEEKLY;BYDAY=MO;UNTIL=" + FormatTime(time.Date(2025, time.April, 14, 23, 59, 59, 0, location)),
+			ExDates:      []time.Time{at(24, 10, 0).AddDate(0, 0, 14)},
+			Created:      created,
+			LastModified: modified,
+		},
+		{
+			UID:          "series-1@roomly",
+			RecurrenceID: &moved,
+			Summary:      "项目周会",
+			Location:     "多功能会议室B",
+			Start:        at(31, 14, 0),
+			End:          at(31, 15, 0),
+			Status:       StatusConfirmed,
+			Created:      created,
+			LastModified: modified,
+		},
+		{
+			UID:          "booking-2@roomly",
+			Summary:      "客户演示; 需求评审, 第二轮",
+			Description:  "一段足够长的会议说明，用来检查超过七十五个字节的行会在不拆开多字节字符的位置折行。",
+			Location:     "多功能会议室A",
+			Start:        at(28, 9, 30),
+			End:          at(28, 10, 30),
+			Status:       StatusCancelled,
+			Created:      created,
+			LastModified: modified,
+		},
+	}
+}
+
+func TestEncodeWithTimezone(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calendar := Calendar{Name: "会议室预定", Refresh: 15 * time.Minute, Location: location, Events: sampleEvents(location)}
+	checkGolden(t, "timezone.ics", calendar.Encode())
+}
+
+func TestEncodeUTC(t *testing.T) {
+	calendar := Calendar{Name: "会议室预定", Events: sampleEvents(time.UTC)}
+	checkGolden(t, "utc.ics", calendar.Encode())
+}
+
+func TestFoldKeepsRunes(t *testing.T) {
+	line := "DESCRIPTION:" + string(bytes.Repeat([]byte("会"), 40))
+	for i, part := range bytes.Split([]byte(fold(line)), []byte("\r\n")) {
+		if len(part) > maxLineOctets {
+			t.Errorf("line %d has %d octets", i, len(part))
+		}
+		if i > 0 && part[0] != ' ' {
+			t.Errorf("continuation line %d does not start with a space", i)
+		}
+	}
+}
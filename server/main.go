@@ -6,24 +6,43 @@ import (
 	"time"
 
 	"roomly/database"
+	"roomly/handlers"
+	"roomly/middleware"
 	"roomly/models"
+	"roomly/outbox"
 	"roomly/routes"
+	"roomly/scheduler"
+	"roomly/webhook"
+
+	"gorm.io/gorm"
 )
 
 func main() {
 	// 初始化数据库
 	database.InitDB()
 
-	// 启动定时任务，每5分钟更新一次已过期预定状态
-	go func() {
-		for {
-			UpdateExpiredBookings()
-			time.Sleep(5 * time.Minute)
-		}
-	}()
+	// 启动定时任务，每分钟释放未签到预定、过期候补和未审批的预定、更新已过期预定状态、展开周期预定、发送会前提醒，
+	// 并投递发件箱中的通知和预定事件回调；每天清理30天前已发送的通知和回调，按配置的本地时间发送每日预定汇总和每周会议安排
+	jobs := scheduler.New()
+	jobs.Every("expire-pending-bookings", time.Minute, handlers.ExpirePendingBookings)
+	jobs.Every("release-no-show-bookings", time.Minute, handlers.ReleaseNoShowBookings)
+	jobs.Every("expire-waitlist-entries", time.Minute, handlers.ExpireWaitlistEntries)
+	jobs.Every("update-expired-bookings", time.Minute, UpdateExpiredBookings)
+	jobs.Every("extend-booking-series", time.Minute, handlers.ExtendBookingSeries)
+	jobs.Every("booking-reminders", time.Minute, handlers.SendBookingReminders)
+	jobs.Every("deliver-notifications", time.Minute, outbox.Deliver)
+	jobs.Every("deliver-webhooks", time.Minute, webhook.Deliver)
+	jobs.Every("prune-notifications", 24*time.Hour, func() {
+		outbox.PruneSent(time.Now().AddDate(0, 0, -30))
+		webhook.PruneDelivered(time.Now().AddDate(0, 0, -30))
+	})
+	jobs.Add("daily-digests", digestSchedule(), handlers.SendDailyDigests)
+	jobs.Add("weekly-agendas", agendaSchedule(), handlers.SendWeeklyAgendas)
+	jobs.Start()
+	defer jobs.Stop()
 
-	// 设置路由
-	r := routes.SetupRoutes()
+	// 设置路由，token 校验结果缓存5分钟
+	r := routes.SetupRoutes(middleware.NewDooTaskVerifier(5 * time.Minute))
 
 	// 获取端口，默认为8080
 	port := os.Getenv("PORT")
@@ -38,8 +57,45 @@ func main() {
 	}
 }
 
+// 每日预定汇总的发送时间，通过环境变量 DIGEST_TIME（HH:MM，默认18:00）配置
+func digestSchedule() scheduler.Daily {
+	value := os.Getenv("DIGEST_TIME")
+	if value == "" {
+		value = "18:00"
+	}
+	daily, err := scheduler.ParseDaily(value)
+	if err != nil {
+		log.Fatal("DIGEST_TIME 配置错误:", err)
+	}
+	return daily
+}
+
+// 每周会议安排的发送时间，通过环境变量 AGENDA_WEEKDAY（MO-SU，默认 MO）和 AGENDA_TIME（HH:MM，默认08:00）配置
+func agendaSchedule() scheduler.Weekly {
+	value := os.Getenv("AGENDA_TIME")
+	if value == "" {
+		value = "08:00"
+	}
+	daily, err := scheduler.ParseDaily(value)
+	if err != nil {
+		log.Fatal("AGENDA_TIME 配置错误:", err)
+	}
+	code := os.Getenv("AGENDA_WEEKDAY")
+	if code == "" {
+		code = "MO"
+	}
+	weekday, ok := models.ParseWeekdayCode(code)
+	if !ok {
+		log.Fatal("AGENDA_WEEKDAY 配置错误:", code)
+	}
+	return scheduler.Weekly{Weekday: weekday, Hour: daily.Hour, Minute: daily.Minute}
+}
+
 // 定时任务：将已过期的active预定状态更新为expired
 func UpdateExpiredBookings() {
+	// 先记录已结束但未签到的预定，避免宽限时间不短于会议时长时被直接标记为过期
+	handlers.ReleaseNoShowBookings()
+
 	now := time.Now()
 	today := now.Format("2006-01-02")
 	currentTime := now.Format("15:04")
@@ -51,7 +107,18 @@ func UpdateExpiredBookings() {
 		Find(&expiredBookings)
 
 	for _, booking := range expiredBookings {
-		booking.Status = "expired"
-		database.DB.Save(&booking)
+		// 条件更新，避免覆盖同时被标记为未签到的预定
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&models.Booking{}).
+				Where("id = ? AND status = ?", booking.ID, "active").
+				Update("status", "expired")
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			return webhook.Publish(tx, webhook.EventExpired, booking.ID)
+		})
+		if err != nil {
+			log.Printf("更新已过期预定%d失败: %v", booking.ID, err)
+		}
 	}
 }
@@ -0,0 +1,247 @@
+// Package message 用 text/template 渲染通知消息。模板按事件类型、接收人类型和语言区分，
+// 默认模板随程序内嵌在 templates/<语言>/<接收人类型>/<事件类型>.tmpl，管理员修改后的模板保存在数据库中并优先使用。
+// templates/<语言>/common.tmpl 定义各模板共用的片段（周期规则描述、变更内容），渲染时与模板一起解析。
+package message
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	"roomly/database"
+	"roomly/models"
+	"roomly/notify"
+)
+
+//go:embed templates
+var defaults embed.FS
+
+// 支持的语言
+const (
+	LocaleZhCN = "zh-CN"
+	LocaleEnUS = "en-US"
+)
+
+// DefaultLocale 未设置语言的接收人和缺少对应语言模板时使用的语言
+const DefaultLocale = LocaleZhCN
+
+// Locales 支持的语言
+var Locales = []string{LocaleZhCN, LocaleEnUS}
+
+// 没有对应事件类型的模板时使用会议提醒模板
+const fallbackEvent = "remind"
+
+// 各语言共用片段的文件名
+const commonFile = "common.tmpl"
+
+// Data 模板中可以使用的消息参数
+type Data struct {
+	Sender      string             // 发送人昵称，会议发起人或审批人
+	Date        string             // 会议日期，周期预定渲染时替换为 series 片段生成的规则描述
+	StartTime   string             // 开始时间
+	EndTime     string             // 结束时间
+	RoomName    string             // 会议室名称
+	Reason      string             // 预定理由
+	Attendees   string             // 参会人员昵称，用、分隔
+	CalendarURL string             // 会议日历文件的地址，未配置 PUBLIC_URL 时为空
+	Series      *notify.Recurrence // 周期预定的规则，单次预定为空
+	Changes     []notify.Change    // 变更内容，在模板中用 {{template "changes" .Changes}} 显示
+	Content     []string           // 事件附带的内容，如取消理由、关闭时间，在模板中用 .Arg 获取
+}
+
+// MeetingTime 会议时间的文字描述
+func (d Data) MeetingTime() string {
+	if d.StartTime != "" && d.EndTime != "" {
+		return fmt.Sprintf("%s %s-%s", d.Date, d.StartTime, d.EndTime)
+	}
+	return d.Date
+}
+
+// Arg 事件附带的第 i 项内容，不存在时为空
+func (d Data) Arg(i int) string {
+	if i < 0 || i >= len(d.Content) {
+		return ""
+	}
+	return d.Content[i]
+}
+
+// Key 模板的事件类型和接收人类型
+type Key struct {
+	Event    string `json:"event"`
+	Audience string `json:"audience"`
+}
+
+// Keys 所有内置模板的事件类型和接收人类型
+func Keys() []Key {
+	var keys []Key
+	root := path.Join("templates", DefaultLocale)
+	fs.WalkDir(defaults, root, func(name string, entry fs.DirEntry, err error) error {
+		// 语言目录下的文件是共用片段，不是消息模板
+		if err != nil || entry.IsDir() || path.Dir(name) == root {
+			return err
+		}
+		audience := path.Base(path.Dir(name))
+		keys = append(keys, Key{Event: strings.TrimSuffix(path.Base(name), ".tmpl"), Audience: audience})
+		return nil
+	})
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Audience != keys[j].Audience {
+			return keys[i].Audience < keys[j].Audience
+		}
+		return keys[i].Event < keys[j].Event
+	})
+	return keys
+}
+
+// IsKnown 判断是否有对应的内置模板
+func IsKnown(event, audience string) bool {
+	_, ok := Default(event, audience, DefaultLocale)
+	return ok
+}
+
+// IsLocale 判断是否为支持的语言
+func IsLocale(locale string) bool {
+	for _, supported := range Locales {
+		if locale == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// Default 内置模板的内容
+func Default(event, audience, locale string) (string, bool) {
+	data, err := defaults.ReadFile(path.Join("templates", locale, audience, event+".tmpl"))
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// Lookup 查找事件使用的模板：优先使用管理员修改的模板，依次回退到参会人员版本、会议提醒模板和默认语言
+func Lookup(event, audience, locale string) (string, error) {
+	audiences := []string{audience}
+	if audience == models.AudienceOrganizer {
+		audiences = append(audiences, models.AudienceAttendee)
+	}
+	for _, loc := range unique(locale, DefaultLocale) {
+		for _, aud := range audiences {
+			for _, ev := range unique(event, fallbackEvent) {
+				var customs []models.MessageTemplate
+				database.DB.Where("event = ? AND audience = ? AND locale = ?", ev, aud, loc).Limit(1).Find(&customs)
+				if len(customs) > 0 {
+					return customs[0].Body, nil
+				}
+				if body, ok := Default(ev, aud, loc); ok {
+					return body, nil
+				}
+			}
+		}
+	}
+	return "", fmt.Errorf("No template for %s/%s/%s", event, audience, locale)
+}
+
+// Render 按事件类型、接收人类型和语言渲染消息
+func Render(event, audience, locale string, data Data) (string, error) {
+	body, err := Lookup(event, audience, locale)
+	if err != nil {
+		return "", err
+	}
+	return Execute(body, locale, data)
+}
+
+// Execute 用消息参数渲染模板内容，模板可以使用该语言的共用片段；模板有语法错误或引用不存在的参数时返回错误
+func Execute(body, locale string, data Data) (string, error) {
+	if strings.TrimSpace(body) == "" {
+		return "", errors.New("Template body is required")
+	}
+	common, err := defaults.ReadFile(path.Join("templates", locale, commonFile))
+	if err != nil {
+		if common, err = defaults.ReadFile(path.Join("templates", DefaultLocale, commonFile)); err != nil {
+			return "", err
+		}
+	}
+	tmpl := template.New("message").Option("missingkey=error")
+	if _, err := tmpl.New("common").Parse(string(common)); err != nil {
+		return "", err
+	}
+	if _, err := tmpl.Parse(body); err != nil {
+		return "", err
+	}
+	// 周期预定的会议日期按语言描述规则
+	if data.Series != nil {
+		var date strings.Builder
+		if err := tmpl.ExecuteTemplate(&date, "series", data.Series); err != nil {
+			return "", err
+		}
+		data.Date = strings.TrimSpace(date.String())
+	}
+	var text strings.Builder
+	if err := tmpl.Execute(&text, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(text.String()), nil
+}
+
+// 各事件附带内容的示例，用于预览
+var sampleContent = map[string][]string{
+	"reminder":       {"15"},
+	"cancel":         {"时间冲突，改期再议"},
+	"closure":        {"2025-01-06", "09:00", "2025-01-06", "18:00", "设备维修"},
+	"summary":        {"1. 确认本周发布计划\n2. 下周一复盘"},
+	"no_show":        {"15"},
+	"waitlist_offer": {"2025-01-06 09:30"},
+	"approved":       {"同意"},
+	"rejected":       {"该时间段已安排部门会议"},
+	"daily_digest":   {"09:00-10:00 | 多功能会议室A | 项目周会 | 张三", "14:00-15:30 | 多功能会议室B | 客户演示 | 李四"},
+	"weekly_agenda":  {"2025-01-06 10:00-11:00 | 多功能会议室A | 项目周会", "2025-01-08 14:00-15:00 | 多功能会议室B | 需求评审"},
+}
+
+// 变更通知的示例变更内容
+var sampleChanges = []notify.Change{
+	{Field: "time", Old: "10:00-11:00", New: "14:00-15:00"},
+	{Field: "privacy", Old: "false", New: "true"},
+}
+
+// Sample 预览模板使用的示例预定
+func Sample(event string) Data {
+	var changes []notify.Change
+	if event == "change" {
+		changes = sampleChanges
+	}
+	return Data{
+		Sender:      "张三",
+		Date:        "2025-01-06",
+		StartTime:   "10:00",
+		EndTime:     "11:00",
+		RoomName:    "多功能会议室A",
+		Reason:      "项目周会",
+		Attendees:   "张三、李四、王五",
+		CalendarURL: "https://roomly.example.com/feeds/0123456789abcdef/calendar.ics",
+		Changes:     changes,
+		Content:     sampleContent[event],
+	}
+}
+
+// 去掉重复项，保持顺序
+func unique(values ...string) []string {
+	var result []string
+	for _, value := range values {
+		found := false
+		for _, existing := range result {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"roomly/database"
+	"roomly/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 上下文中保存当前会员和 token 的键
+const (
+	memberKey = "roomly.member"
+	tokenKey  = "roomly.token"
+)
+
+// Identity 由 token 解析出的 DooTask 用户身份
+type Identity struct {
+	DootaskID uint
+	Nickname  string
+	IsAdmin   bool // DooTask 系统管理员
+}
+
+// TokenVerifier 将 token 解析为 DooTask 用户身份，测试时可替换为本地实现
+type TokenVerifier interface {
+	Verify(token string) (*Identity, error)
+}
+
+// DooTaskVerifier 通过 DooTask 接口校验 token，并在 TTL 内缓存校验结果
+type DooTaskVerifier struct {
+	ttl   time.Duration
+	mu    sync.Mutex
+	cache map[string]cachedIdentity
+}
+
+type cachedIdentity struct {
+	identity  Identity
+	expiresAt time.Time
+}
+
+func NewDooTaskVerifier(ttl time.Duration) *DooTaskVerifier {
+	return &DooTaskVerifier{ttl: ttl, cache: make(map[string]cachedIdentity)}
+}
+
+func (v *DooTaskVerifier) Verify(token string) (*Identity, error) {
+	v.mu.Lock()
+	if cached, ok := v.cache[token]; ok && time.Now().Before(cached.expiresAt) {
+		v.mu.Unlock()
+		identity := cached.identity
+		return &identity, nil
+	}
+	v.mu.Unlock()
+
+	client := models.NewDooTaskClient(token)
+	user, err := client.Client.GetUserInfo()
+	if err != nil {
+		return nil, err
+	}
+	if user.Userid <= 0 {
+		return nil, errors.New("invalid dootask user")
+	}
+	identity := Identity{DootaskID: uint(user.Userid), Nickname: user.Nickname}
+	for _, role := range user.Identity {
+		if role == "admin" {
+			identity.IsAdmin = true
+		}
+	}
+
+	v.mu.Lock()
+	// 顺带清理过期缓存，避免无限增长
+	now := time.Now()
+	for key, cached := range v.cache {
+		if now.After(cached.expiresAt) {
+			delete(v.cache, key)
+		}
+	}
+	v.cache[token] = cachedIdentity{identity: identity, expiresAt: now.Add(v.ttl)}
+	v.mu.Unlock()
+	return &identity, nil
+}
+
+// ExtractToken 从 Authorization header 获取 token，兼容 Bearer token；
+// 不接受查询参数中的 token，避免会话 token 出现在访问日志和浏览器历史中
+func ExtractToken(c *gin.Context) string {
+	authHeader := c.GetHeader("Authorization")
+	if strings.HasPrefix(authHeader, "Bearer ") {
+		return authHeader[7:]
+	}
+	return authHeader
+}
+
+// Auth 校验 token 并将对应的会员绑定到请求上下文，会员不存在时自动创建
+func Auth(verifier TokenVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := ExtractToken(c)
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing authorization token"})
+			return
+		}
+
+		identity, err := verifier.Verify(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization token"})
+			return
+		}
+
+		member, err := findOrCreateMember(identity)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve member"})
+			return
+		}
+
+		c.Set(memberKey, member)
+		c.Set(tokenKey, token)
+		c.Next()
+	}
+}
+
+// CurrentMember 获取当前请求的会员，未经过 Auth 中间件时返回 nil
+func CurrentMember(c *gin.Context) *models.Member {
+	if value, ok := c.Get(memberKey); ok {
+		if member, ok := value.(*models.Member); ok {
+			return member
+		}
+	}
+	return nil
+}
+
+// Token 获取当前请求的 token
+func Token(c *gin.Context) string {
+	if value, ok := c.Get(tokenKey); ok {
+		if token, ok := value.(string); ok {
+			return token
+		}
+	}
+	return ExtractToken(c)
+}
+
+// 根据 DooTask ID 查找会员，不存在时自动创建；
+// 昵称与 DooTask 保持同步，DooTask 系统管理员自动获得管理员权限（不会自动撤销手动授予的权限）
+func findOrCreateMember(identity *Identity) (*models.Member, error) {
+	member := models.Member{DootaskID: identity.DootaskID}
+	err := database.DB.Where("dootask_id = ?", identity.DootaskID).
+		Attrs(models.Member{Name: identity.Nickname, IsAdmin: identity.IsAdmin}).
+		FirstOrCreate(&member).Error
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if identity.Nickname != "" && member.Name != identity.Nickname {
+		updates["name"] = identity.Nickname
+	}
+	if identity.IsAdmin && !member.IsAdmin {
+		updates["is_admin"] = true
+	}
+	if len(updates) > 0 {
+		if err := database.DB.Model(&member).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// 加载管理的会议室，用于会议室级别的权限判断
+	if err := database.DB.Model(&member).Association("ManagedRooms").Find(&member.ManagedRooms); err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
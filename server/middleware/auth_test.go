@@ -0,0 +1,113 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"roomly/database"
+	"roomly/middleware"
+	"roomly/models"
+	"roomly/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 经过 Auth 中间件的路由，返回当前会员和 token
+func authRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Auth(testutil.Verifier{
+		"alice": {DootaskID: 1, Nickname: "alice"},
+		"root":  {DootaskID: 2, Nickname: "root", IsAdmin: true},
+	}))
+	router.GET("/me", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"member": middleware.CurrentMember(c), "token": middleware.Token(c)})
+	})
+	return router
+}
+
+func TestAuthRejectsMissingOrInvalidToken(t *testing.T) {
+	testutil.OpenDB(t)
+	router := authRouter()
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+	}{
+		{"missing", "/me", ""},
+		{"query parameter", "/me?token=alice", ""},
+		{"unknown", "/me", "Bearer mallory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			if recorder.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func TestAuthBindsMember(t *testing.T) {
+	testutil.OpenDB(t)
+	router := authRouter()
+
+	for _, header := range []string{"Bearer alice", "alice"} {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", header)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Authorization %q: status = %d: %s", header, recorder.Code, recorder.Body)
+		}
+		var body struct {
+			Member models.Member `json:"member"`
+			Token  string        `json:"token"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Member.DootaskID != 1 || body.Token != "alice" {
+			t.Errorf("Authorization %q: member %d, token %q", header, body.Member.DootaskID, body.Token)
+		}
+	}
+
+	// 同一用户只创建一个会员
+	var members []models.Member
+	database.DB.Where("dootask_id = ?", 1).Find(&members)
+	if len(members) != 1 {
+		t.Fatalf("found %d members, want 1", len(members))
+	}
+	if members[0].Name != "alice" || members[0].IsAdmin {
+		t.Errorf("member = %+v, want non-admin alice", members[0])
+	}
+}
+
+func TestAuthGrantsDooTaskAdmin(t *testing.T) {
+	testutil.OpenDB(t)
+	router := authRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer root")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+	}
+
+	var member models.Member
+	if err := database.DB.Where("dootask_id = ?", 2).First(&member).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !member.IsAdmin {
+		t.Error("DooTask admin was not granted admin")
+	}
+}
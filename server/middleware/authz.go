@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"roomly/policy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole 要求当前会员至少具备指定角色
+func RequireRole(role policy.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := policy.RequireRole(CurrentMember(c), role); err != nil {
+			AbortWithPolicyError(c, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// AbortWithPolicyError 将权限错误统一转换为 401/403 响应
+func AbortWithPolicyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, policy.ErrUnauthenticated):
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+	case errors.Is(err, policy.ErrForbidden):
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
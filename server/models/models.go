@@ -10,35 +10,134 @@ type Member struct {
 	Name        string    `gorm:"not null" json:"name"`
 	DootaskID   uint      `gorm:"not null" json:"dootask_id"`
 	IsAdmin     bool      `gorm:"default:false" json:"is_admin"`
-	IsRoomAdmin bool      `gorm:"default:false" json:"is_room_admin"`
+	IsRoomAdmin bool      `gorm:"default:false" json:"is_room_admin"` // 是否管理至少一个会议室，由会议室管理员分配维护
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	// 关联关系
+	ManagedRooms []Room `gorm:"many2many:room_admins;" json:"managed_rooms,omitempty"`
+}
+
+// 会员通知偏好模型，每个会员最多一条记录；没有记录时使用默认设置
+type MemberPreference struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	MemberID        uint      `gorm:"not null;uniqueIndex" json:"member_id"`
+	ReminderMinutes int       `gorm:"not null" json:"reminder_minutes"`           // 会议开始前多少分钟提醒，0 表示不提醒
+	Channels        string    `gorm:"not null;default:dootask" json:"channels"`   // 接收通知的渠道，逗号分隔：dootask、email、webhook
+	Email           string    `json:"email"`                                      // email 渠道的邮件地址
+	WebhookURL      string    `json:"webhook_url"`                                // webhook 渠道的回调地址
+	Locale          string    `gorm:"not null;default:zh-CN" json:"locale"`       // 通知消息的语言：zh-CN、en-US
+	DailyDigest     bool      `gorm:"not null;default:false" json:"daily_digest"` // 会议室管理员每天接收所管理会议室次日预定的汇总
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
 }
 
 // 会议室模型
 type Room struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Name        string    `gorm:"not null" json:"name"`
-	Description string    `json:"description"`
-	Capacity    int       `gorm:"not null" json:"capacity"`
-	IsOpen      bool      `gorm:"default:true" json:"is_open"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	Name             string    `gorm:"not null" json:"name"`
+	Description      string    `json:"description"`
+	Capacity         int       `gorm:"not null" json:"capacity"`
+	IsOpen           bool      `gorm:"default:true" json:"is_open"`
+	SlotMinutes      int       `gorm:"default:30" json:"slot_minutes"`         // 时间段长度: 15, 30, 60
+	MinDuration      int       `gorm:"default:0" json:"min_duration"`          // 最短预定时长（分钟），0 表示不限
+	MaxDuration      int       `gorm:"default:0" json:"max_duration"`          // 最长预定时长（分钟），0 表示不限
+	HolidayPolicy    string    `gorm:"default:warn" json:"holiday_policy"`     // 非工作日预定策略: allow, warn, reject
+	CapacityPolicy   string    `gorm:"default:warn" json:"capacity_policy"`    // 超员策略: warn, reject, override
+	CheckInGrace     int       `gorm:"default:0" json:"check_in_grace"`        // 开始后未签到自动释放的宽限时间（分钟），0 表示不要求签到
+	RequiresApproval bool      `gorm:"default:false" json:"requires_approval"` // 预定是否需要会议室管理员审批
+	Building         string    `json:"building"`                               // 所在楼栋
+	Floor            string    `json:"floor"`                                  // 所在楼层
+	Area             string    `json:"area"`                                   // 所在区域，如东区、A 区
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+
+	// 关联关系
+	Admins        []Member           `gorm:"many2many:room_admins;" json:"admins,omitempty"`
+	BusinessHours []RoomBusinessHour `gorm:"foreignKey:RoomID" json:"business_hours"`
+	Amenities     []Amenity          `gorm:"many2many:room_amenities;" json:"amenities"`
+	Photos        []RoomPhoto        `gorm:"foreignKey:RoomID" json:"photos"`
+}
+
+// 会议室设施模型，如投影仪、视频会议；按 code 筛选会议室
+type Amenity struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Code      string    `gorm:"not null;uniqueIndex" json:"code"`
+	Name      string    `gorm:"not null" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 会议室照片模型
+type RoomPhoto struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	RoomID    uint      `gorm:"not null;index" json:"room_id"`
+	URL       string    `gorm:"not null" json:"url"`
+	Caption   string    `json:"caption"`
+	SortOrder int       `gorm:"default:0" json:"sort_order"` // 越小越靠前
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 会议室营业时间模型，每个星期一条记录；会议室没有任何记录时全天开放
+type RoomBusinessHour struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	RoomID    uint      `gorm:"not null;index" json:"room_id"`
+	Weekday   int       `gorm:"not null" json:"weekday"` // 0-6，0 表示周日
+	Open      string    `gorm:"not null" json:"open"`    // 格式: HH:MM
+	Close     string    `gorm:"not null" json:"close"`   // 格式: HH:MM，24:00 表示营业到当天结束
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 节假日日历模型，每天最多一条记录；kind 为 holiday（节假日）或 workday（调休工作日）
+type CalendarDay struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Date      string    `gorm:"not null;uniqueIndex" json:"date"` // 格式: YYYY-MM-DD
+	Kind      string    `gorm:"not null" json:"kind"`
+	Name      string    `json:"name"` // 如 国庆节、国庆节补班
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 会议室维护/关闭时间段模型，从开始日期的开始时间连续关闭到结束日期的结束时间
+type RoomBlackout struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	RoomID    uint      `gorm:"not null;index" json:"room_id"`
+	MemberID  uint      `gorm:"not null" json:"member_id"`  // 创建人
+	StartDate string    `gorm:"not null" json:"start_date"` // 格式: YYYY-MM-DD
+	StartTime string    `gorm:"not null" json:"start_time"` // 格式: HH:MM
+	EndDate   string    `gorm:"not null" json:"end_date"`   // 格式: YYYY-MM-DD
+	EndTime   string    `gorm:"not null" json:"end_time"`   // 格式: HH:MM，24:00 表示到当天结束
+	Reason    string    `gorm:"not null" json:"reason"`     // 关闭原因，如装修、设备检修
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// 关联关系
+	Room   Room   `gorm:"foreignKey:RoomID" json:"room"`
+	Member Member `gorm:"foreignKey:MemberID" json:"member"`
 }
 
 // 预定记录模型
 type Booking struct {
-	ID           uint      `gorm:"primaryKey" json:"id"`
-	RoomID       uint      `gorm:"not null" json:"room_id"`
-	MemberID     uint      `gorm:"not null" json:"member_id"`
-	Date         string    `gorm:"not null" json:"date"`       // 格式: YYYY-MM-DD
-	StartTime    string    `gorm:"not null" json:"start_time"` // 格式: HH:MM
-	EndTime      string    `gorm:"not null" json:"end_time"`   // 格式: HH:MM
-	Reason       string    `gorm:"not null" json:"reason"`
-	CancelReason string    `json:"cancel_reason"`                // 取消理由
-	Status       string    `gorm:"default:active" json:"status"` // active, cancelled
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	RoomID       uint       `gorm:"not null" json:"room_id"`
+	MemberID     uint       `gorm:"not null" json:"member_id"`
+	Date         string     `gorm:"not null" json:"date"`       // 格式: YYYY-MM-DD
+	StartTime    string     `gorm:"not null" json:"start_time"` // 格式: HH:MM
+	EndTime      string     `gorm:"not null" json:"end_time"`   // 格式: HH:MM，24:00 表示到当天结束
+	Reason       string     `gorm:"not null" json:"reason"`
+	IsPrivate    bool       `gorm:"default:false" json:"is_private"` // 私密会议，预定理由和参会人员仅对参会人员和会议室管理员可见
+	CancelReason string     `json:"cancel_reason"`                   // 取消理由
+	Status       string     `gorm:"default:active" json:"status"`    // pending, active, rejected, cancelled, expired, no_show
+	SeriesID     *uint      `gorm:"index" json:"series_id"`          // 所属周期预定，单次预定为空
+	ReviewedBy   *uint      `json:"reviewed_by"`                     // 审批人，未审批为空
+	ReviewedAt   *time.Time `json:"reviewed_at"`                     // 审批时间
+	ReviewReason string     `json:"review_reason"`                   // 审批意见（拒绝理由）
+	CheckedInAt  *time.Time `json:"checked_in_at"`                   // 签到时间，未签到为空
+	CheckedInBy  uint       `json:"checked_in_by"`                   // 签到人的 dootask_id
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
 
 	// 关联关系
 	Room         Room          `gorm:"foreignKey:RoomID" json:"room"`
@@ -56,25 +155,285 @@ type BookingUser struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-// 预定请求结构
-type BookingRequest struct {
+// 时间段占用模型，每条记录表示一个有效预定占用的最小时间单元；
+// 唯一索引保证同一会议室同一时间单元只能被一个预定占用
+type BookingSlot struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	BookingID uint   `gorm:"not null;index" json:"booking_id"`
+	RoomID    uint   `gorm:"not null;uniqueIndex:idx_room_date_minute" json:"room_id"`
+	Date      string `gorm:"not null;uniqueIndex:idx_room_date_minute" json:"date"`   // 格式: YYYY-MM-DD
+	Minute    int    `gorm:"not null;uniqueIndex:idx_room_date_minute" json:"minute"` // 当天的第几分钟
+}
+
+// 预定变更记录模型，每个变更字段一条记录
+type BookingChange struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	BookingID uint      `gorm:"not null;index" json:"booking_id"`
+	MemberID  uint      `gorm:"not null" json:"member_id"` // 操作人
+	Field     string    `gorm:"not null" json:"field"`     // room, date, time, reason, privacy, attendees
+	OldValue  string    `json:"old_value"`
+	NewValue  string    `json:"new_value"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// 关联关系
+	Member Member `gorm:"foreignKey:MemberID" json:"member"`
+}
+
+// 会前提醒发送记录模型，同一预定同一开始时间每个参会人员只提醒一次，改期后重新提醒
+type BookingReminder struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	BookingID   uint      `gorm:"not null;uniqueIndex:idx_booking_reminder" json:"booking_id"`
+	Userid      uint      `gorm:"not null;uniqueIndex:idx_booking_reminder" json:"userid"`
+	StartsAt    string    `gorm:"not null;uniqueIndex:idx_booking_reminder" json:"starts_at"` // 提醒时会议的开始时间，格式: YYYY-MM-DD HH:MM
+	LeadMinutes int       `gorm:"not null" json:"lead_minutes"`                               // 提前提醒的分钟数
+	SentAt      time.Time `json:"sent_at"`
+}
+
+// 通知发件箱模型，每个接收人一条记录；与业务数据在同一事务中写入，由后台任务投递，失败时按指数退避重试
+type Notification struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Userid        uint       `gorm:"not null;index" json:"userid"`            // 接收人的 dootask_id
+	Audience      string     `gorm:"not null" json:"audience"`                // attendee（参会人员）、organizer（预定人）或 admin（会议室管理员）
+	Channel       string     `gorm:"not null;default:dootask" json:"channel"` // 发送渠道：dootask、email、webhook
+	MsgType       string     `gorm:"not null" json:"msg_type"`                // 事件类型，对应消息模板
+	Payload       string     `gorm:"type:text" json:"payload"`                // 消息参数 JSON
+	Token         string     `json:"-"`                                       // 发送消息使用的 token，为空时使用 DOOTASK_BOT_TOKEN
+	Status        string     `gorm:"default:pending;index" json:"status"`     // pending, sent, dead
+	Attempts      int        `gorm:"default:0" json:"attempts"`               // 已尝试投递的次数
+	NextAttemptAt time.Time  `gorm:"not null;index" json:"next_attempt_at"`   // 下次尝试投递的时间
+	LastError     string     `json:"last_error"`                              // 最近一次投递失败的原因
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// 通知消息模板模型，管理员修改后的模板，覆盖同一事件类型、接收人类型和语言的内置模板
+type MessageTemplate struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Event     string    `gorm:"not null;uniqueIndex:idx_message_template" json:"event"`    // 事件类型
+	Audience  string    `gorm:"not null;uniqueIndex:idx_message_template" json:"audience"` // attendee、organizer 或 admin
+	Locale    string    `gorm:"not null;uniqueIndex:idx_message_template" json:"locale"`   // zh-CN、en-US
+	Body      string    `gorm:"type:text;not null" json:"body"`                            // text/template 模板内容
+	UpdatedBy uint      `json:"updated_by"`                                                // 最后修改的会员
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 外部系统的预定事件回调订阅，预定创建、修改、取消、过期和未签到释放时向 URL POST 完整的预定 JSON
+type WebhookSubscription struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"not null" json:"name"`
+	URL       string    `gorm:"not null" json:"url"`
+	Events    string    `json:"events"`                    // 订阅的事件，逗号分隔，为空表示全部事件
+	Secret    string    `gorm:"not null" json:"secret"`    // 签名密钥，请求头 X-Roomly-Signature 为请求体的 HMAC-SHA256
+	IsActive  bool      `gorm:"not null" json:"is_active"` // 停用后不再产生新的投递
+	CreatedBy uint      `json:"created_by"`                // 创建的会员
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 预定事件回调投递记录，与预定变更在同一事务中写入，由后台任务投递，失败时按指数退避重试
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	SubscriptionID uint       `gorm:"not null;index" json:"subscription_id"`
+	Event          string     `gorm:"not null" json:"event"`                 // booking.created、booking.updated、booking.cancelled、booking.expired、booking.no_show、ping
+	BookingID      uint       `gorm:"index" json:"booking_id"`               // 测试事件为 0
+	Payload        string     `gorm:"type:text" json:"payload"`              // 请求体 JSON
+	Status         string     `gorm:"default:pending;index" json:"status"`   // pending, sent, dead
+	Attempts       int        `gorm:"default:0" json:"attempts"`             // 已尝试投递的次数
+	NextAttemptAt  time.Time  `gorm:"not null;index" json:"next_attempt_at"` // 下次尝试投递的时间
+	ResponseStatus int        `json:"response_status"`                       // 最近一次请求的响应状态码，请求失败为 0
+	ResponseBody   string     `gorm:"type:text" json:"response_body"`        // 最近一次请求的响应内容，截取前 1KB
+	LastError      string     `json:"last_error"`                            // 最近一次投递失败的原因
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// 日历订阅模型，通过不可猜测的 token 访问，日历客户端无需登录即可订阅；
+// member 为会员参加的会议，room 为会议室的所有预定，booking 为单个预定（附在会议通知中）
+type CalendarFeed struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	Token          string     `gorm:"not null;uniqueIndex" json:"token"`
+	Kind           string     `gorm:"not null" json:"kind"`            // member, room, booking
+	MemberID       uint       `gorm:"not null;index" json:"member_id"` // 创建订阅的会员，按其权限显示私密会议
+	RoomID         *uint      `gorm:"index" json:"room_id"`            // room 订阅的会议室
+	BookingID      *uint      `gorm:"index" json:"booking_id"`         // booking 订阅的预定
+	LastAccessedAt *time.Time `json:"last_accessed_at"`                // 最近一次被日历客户端获取的时间
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// 关联关系
+	Room *Room `gorm:"foreignKey:RoomID" json:"room,omitempty"`
+}
+
+// 候补记录模型，时间段被释放时按先后顺序自动预定或通知候补会员在限定时间内认领
+type WaitlistEntry struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	RoomID         uint       `gorm:"not null;index" json:"room_id"`
+	MemberID       uint       `gorm:"not null;index" json:"member_id"`
+	Date           string     `gorm:"not null" json:"date"`       // 格式: YYYY-MM-DD
+	StartTime      string     `gorm:"not null" json:"start_time"` // 格式: HH:MM
+	EndTime        string     `gorm:"not null" json:"end_time"`   // 格式: HH:MM，24:00 表示到当天结束
+	Reason         string     `gorm:"not null" json:"reason"`
+	AutoBook       bool       `json:"auto_book"`                     // 时间段释放时直接预定，否则发送认领通知
+	Status         string     `gorm:"default:waiting" json:"status"` // waiting, offered, booked, withdrawn, expired
+	OfferExpiresAt *time.Time `json:"offer_expires_at"`              // 认领截止时间，仅 offered 状态有效
+	BookingID      *uint      `json:"booking_id"`                    // 候补成功后创建的预定
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// 关联关系
+	Room   Room           `gorm:"foreignKey:RoomID" json:"room"`
+	Member Member         `gorm:"foreignKey:MemberID" json:"member"`
+	Users  []WaitlistUser `gorm:"foreignKey:EntryID" json:"booking_users"`
+}
+
+// 候补参会人员模型，候补成功时作为预定的参会人员
+type WaitlistUser struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	EntryID  uint   `gorm:"not null;index" json:"entry_id"`
+	Userid   uint   `gorm:"not null" json:"userid"`
+	Nickname string `gorm:"not null" json:"nickname"`
+}
+
+// 候补请求结构
+type WaitlistRequest struct {
 	RoomID       uint          `json:"room_id" binding:"required"`
-	MemberID     uint          `json:"member_id" binding:"required"`
 	Date         string        `json:"date" binding:"required"`
 	TimeSlots    []string      `json:"time_slots" binding:"required"`
 	Reason       string        `json:"reason" binding:"required"`
-	BookingUsers []BookingUser `json:"booking_users" binding:"required"`
+	BookingUsers []BookingUser `json:"booking_users"`
+	AutoBook     bool          `json:"auto_book"`
+}
+
+// 预定配额策略模型，RoomID 为空时适用于所有会议室；适用于同一会员的所有策略都需要满足，
+// 会议室管理员和管理员只受 applies_to 为 admin 的策略限制，没有此类策略时不受限
+type QuotaPolicy struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	Name              string    `gorm:"not null" json:"name"`
+	RoomID            *uint     `gorm:"index" json:"room_id"`
+	AppliesTo         string    `gorm:"not null;default:member" json:"applies_to"` // member, admin
+	MaxActiveBookings int       `gorm:"default:0" json:"max_active_bookings"`      // 最多同时持有的未结束预定数，0 表示不限
+	MaxWeeklyMinutes  int       `gorm:"default:0" json:"max_weekly_minutes"`       // 每个会议室每周最多预定的分钟数，0 表示不限
+	MaxDuration       int       `gorm:"default:0" json:"max_duration"`             // 单次预定最长分钟数，0 表示不限
+	MinLeadMinutes    int       `gorm:"default:0" json:"min_lead_minutes"`         // 至少提前多少分钟预定，0 表示不限
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+
+	// 关联关系
+	Room *Room `gorm:"foreignKey:RoomID" json:"room,omitempty"`
+}
+
+// 预定请求结构
+type BookingRequest struct {
+	RoomID           uint          `json:"room_id" binding:"required"`
+	MemberID         uint          `json:"member_id"` // 已废弃，预定人以登录会员为准
+	Date             string        `json:"date" binding:"required"`
+	TimeSlots        []string      `json:"time_slots" binding:"required"`
+	Reason           string        `json:"reason" binding:"required"`
+	IsPrivate        bool          `json:"is_private"`
+	BookingUsers     []BookingUser `json:"booking_users" binding:"required"`
+	CapacityOverride bool          `json:"capacity_override"`  // 会议室管理员确认超员
+	BlockOwnConflict bool          `json:"block_own_conflict"` // 预定人自己同一时间已有会议时拒绝预定
+}
+
+// 修改预定请求结构，未传的字段保持不变
+type BookingUpdateRequest struct {
+	RoomID           uint          `json:"room_id"`
+	Date             string        `json:"date"`
+	TimeSlots        []string      `json:"time_slots"`
+	Reason           string        `json:"reason"`
+	IsPrivate        *bool         `json:"is_private"`
+	BookingUsers     []BookingUser `json:"booking_users"`
+	CapacityOverride bool          `json:"capacity_override"`  // 会议室管理员确认超员
+	BlockOwnConflict bool          `json:"block_own_conflict"` // 预定人自己同一时间已有会议时拒绝预定
+}
+
+// 周期预定模型，按规则展开为多条 Booking
+type BookingSeries struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	RoomID            uint      `gorm:"not null" json:"room_id"`
+	MemberID          uint      `gorm:"not null" json:"member_id"`
+	Freq              string    `gorm:"not null" json:"freq"`       // daily, weekly, monthly
+	Interval          int       `gorm:"default:1" json:"interval"`  // 间隔，例如每2周
+	ByWeekday         string    `json:"by_weekday"`                 // 逗号分隔: MO,TU,WE,TH,FR,SA,SU，仅 weekly 使用
+	StartDate         string    `gorm:"not null" json:"start_date"` // 格式: YYYY-MM-DD
+	Until             string    `json:"until"`                      // 格式: YYYY-MM-DD，为空表示不限
+	Count             int       `json:"count"`                      // 总次数，0 表示不限
+	StartTime         string    `gorm:"not null" json:"start_time"` // 格式: HH:MM
+	EndTime           string    `gorm:"not null" json:"end_time"`   // 格式: HH:MM
+	Reason            string    `gorm:"not null" json:"reason"`
+	MaterializedUntil string    `json:"materialized_until"`           // 已展开到的日期
+	Status            string    `gorm:"default:active" json:"status"` // active, cancelled
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+
+	// 关联关系
+	Room   Room                `gorm:"foreignKey:RoomID" json:"room"`
+	Member Member              `gorm:"foreignKey:MemberID" json:"member"`
+	Users  []BookingSeriesUser `gorm:"foreignKey:SeriesID" json:"users"`
+}
+
+// 周期预定参会人员模型，用于展开后续发生的预定
+type BookingSeriesUser struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	SeriesID  uint      `gorm:"not null;index" json:"series_id"`
+	Userid    uint      `gorm:"not null" json:"userid"`
+	Nickname  string    `gorm:"not null" json:"nickname"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 周期预定请求结构
+type BookingSeriesRequest struct {
+	RoomID           uint          `json:"room_id" binding:"required"`
+	StartDate        string        `json:"start_date" binding:"required"`
+	TimeSlots        []string      `json:"time_slots" binding:"required"`
+	Reason           string        `json:"reason" binding:"required"`
+	BookingUsers     []BookingUser `json:"booking_users" binding:"required"`
+	Freq             string        `json:"freq" binding:"required"`
+	Interval         int           `json:"interval"`
+	ByWeekday        []string      `json:"by_weekday"`
+	Until            string        `json:"until"`
+	Count            int           `json:"count"`
+	CapacityOverride bool          `json:"capacity_override"`  // 会议室管理员确认超员
+	BlockOwnConflict bool          `json:"block_own_conflict"` // 预定人自己同一时间已有会议时跳过该发生
+}
+
+// 周期预定修改请求结构，scope 支持 this、following、all
+type BookingSeriesUpdateRequest struct {
+	Scope            string        `json:"scope" binding:"required"`
+	TimeSlots        []string      `json:"time_slots"`
+	Reason           string        `json:"reason"`
+	BookingUsers     []BookingUser `json:"booking_users"`
+	CapacityOverride bool          `json:"capacity_override"`  // 会议室管理员确认超员
+	BlockOwnConflict bool          `json:"block_own_conflict"` // 预定人自己同一时间已有会议时拒绝修改
+}
+
+// 会议室关闭时间段请求结构，end_date 为空时与 start_date 相同
+type RoomBlackoutRequest struct {
+	StartDate       string `json:"start_date" binding:"required"`
+	StartTime       string `json:"start_time" binding:"required"`
+	EndDate         string `json:"end_date"`
+	EndTime         string `json:"end_time" binding:"required"`
+	Reason          string `json:"reason" binding:"required"`
+	CancelConflicts bool   `json:"cancel_conflicts"` // 是否自动取消冲突的预定
 }
 
 // 时间段结构
 type TimeSlot struct {
-	Start    string `json:"start"`
-	End      string `json:"end"`
-	IsBooked bool   `json:"is_booked"`
+	Start     string `json:"start"`
+	End       string `json:"end"`
+	IsBooked  bool   `json:"is_booked"`
+	IsBlocked bool   `json:"is_blocked,omitempty"` // 处于会议室关闭时间段内（同时标记为已预定）
+	IsPending bool   `json:"is_pending,omitempty"` // 被待审批的预定占用（同时标记为已预定）
 }
 
 // 可用时间段响应
 type AvailableSlots struct {
-	Date      string     `json:"date"`
-	TimeSlots []TimeSlot `json:"time_slots"`
+	Date        string     `json:"date"`
+	TimeSlots   []TimeSlot `json:"time_slots"`
+	IsHoliday   bool       `json:"is_holiday,omitempty"`   // 非工作日（节假日或非调休的周末）
+	HolidayName string     `json:"holiday_name,omitempty"` // 节假日名称
 }
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"roomly/quota"
+)
+
+// Limits 配额策略的各项限制
+func (p *QuotaPolicy) Limits() quota.Limits {
+	return quota.Limits{
+		MaxActiveBookings: p.MaxActiveBookings,
+		MaxWeeklyMinutes:  p.MaxWeeklyMinutes,
+		MaxDuration:       p.MaxDuration,
+		MinLeadMinutes:    p.MinLeadMinutes,
+	}
+}
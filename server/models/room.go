@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+
+	"roomly/timeslot"
+)
+
+// 会议室非工作日预定策略
+const (
+	HolidayAllow  = "allow"
+	HolidayWarn   = "warn"
+	HolidayReject = "reject"
+)
+
+// 会议室超员策略，参会人数超过容量时生效
+const (
+	CapacityWarn     = "warn"
+	CapacityReject   = "reject"
+	CapacityOverride = "override" // 仅会议室管理员确认后可以超员
+)
+
+// SlotLength 会议室的时间段长度（分钟），未配置或配置无效时使用默认值
+func (r *Room) SlotLength() int {
+	if timeslot.IsSupportedLength(r.SlotMinutes) {
+		return r.SlotMinutes
+	}
+	return timeslot.DefaultLength
+}
+
+// OpeningHours 会议室在指定星期的营业时间；未配置任何营业时间时全天开放，
+// 配置了营业时间但当天没有记录表示当天不开放
+func (r *Room) OpeningHours(weekday time.Weekday) (timeslot.Window, bool) {
+	if len(r.BusinessHours) == 0 {
+		return timeslot.Window{Start: 0, End: timeslot.DayMinutes}, true
+	}
+	for _, hours := range r.BusinessHours {
+		if hours.Weekday == int(weekday) {
+			window, err := timeslot.ParseWindow(hours.Open, hours.Close)
+			if err != nil {
+				return timeslot.Window{}, false
+			}
+			return window, true
+		}
+	}
+	return timeslot.Window{}, false
+}
+
+// TimeSlotsOn 生成会议室在指定日期营业时间内的所有时间段
+func (r *Room) TimeSlotsOn(date time.Time) []timeslot.Window {
+	hours, ok := r.OpeningHours(date.Weekday())
+	if !ok {
+		return nil
+	}
+	return timeslot.Generate(hours, r.SlotLength())
+}
+
+// WindowOn 关闭时间段在指定日期（格式: YYYY-MM-DD）覆盖的时间区间
+func (b *RoomBlackout) WindowOn(date string) (timeslot.Window, bool) {
+	if date < b.StartDate || date > b.EndDate {
+		return timeslot.Window{}, false
+	}
+	window := timeslot.Window{Start: 0, End: timeslot.DayMinutes}
+	if date == b.StartDate {
+		start, err := timeslot.Parse(b.StartTime)
+		if err != nil {
+			return timeslot.Window{}, false
+		}
+		window.Start = start
+	}
+	if date == b.EndDate {
+		end, err := timeslot.Parse(b.EndTime)
+		if err != nil {
+			return timeslot.Window{}, false
+		}
+		window.End = end
+	}
+	return window, window.Start < window.End
+}
+
+// Describe 关闭时间段的文字描述，用于接口错误信息；消息通知中由模板按语言描述
+func (b *RoomBlackout) Describe() string {
+	if b.StartDate == b.EndDate {
+		return b.StartDate + " " + b.StartTime + "-" + b.EndTime
+	}
+	return b.StartDate + " " + b.StartTime + " to " + b.EndDate + " " + b.EndTime
+}
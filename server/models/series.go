@@ -0,0 +1,152 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 周期预定支持的频率
+const (
+	FreqDaily   = "daily"
+	FreqWeekly  = "weekly"
+	FreqMonthly = "monthly"
+)
+
+// 星期缩写与 time.Weekday 的对应关系（与 RRULE BYDAY 一致）
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// IsValidWeekdayCode 判断星期缩写是否合法
+func IsValidWeekdayCode(code string) bool {
+	_, ok := weekdayCodes[strings.ToUpper(code)]
+	return ok
+}
+
+// ParseWeekdayCode 将星期缩写（MO、TU 等）转换为 time.Weekday
+func ParseWeekdayCode(code string) (time.Weekday, bool) {
+	day, ok := weekdayCodes[strings.ToUpper(strings.TrimSpace(code))]
+	return day, ok
+}
+
+// Weekdays 解析 ByWeekday，未设置时默认使用开始日期所在的星期
+func (s *BookingSeries) Weekdays() []time.Weekday {
+	var days []time.Weekday
+	for _, code := range strings.Split(s.ByWeekday, ",") {
+		if day, ok := weekdayCodes[strings.ToUpper(strings.TrimSpace(code))]; ok {
+			days = append(days, day)
+		}
+	}
+	if len(days) == 0 {
+		if start, err := time.Parse("2006-01-02", s.StartDate); err == nil {
+			days = append(days, start.Weekday())
+		}
+	}
+	return days
+}
+
+// Occurrences 按规则展开 [from, to] 范围内的发生日期（格式: YYYY-MM-DD）
+// 展开始终从 StartDate 开始计数，保证 Count 在多次展开之间保持一致
+func (s *BookingSeries) Occurrences(from, to string) []string {
+	start, err := time.Parse("2006-01-02", s.StartDate)
+	if err != nil {
+		return nil
+	}
+	end, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return nil
+	}
+	if s.Until != "" {
+		if until, err := time.Parse("2006-01-02", s.Until); err == nil && until.Before(end) {
+			end = until
+		}
+	}
+
+	interval := s.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	weekdays := make(map[time.Weekday]bool)
+	for _, day := range s.Weekdays() {
+		weekdays[day] = true
+	}
+	// weekly 以开始日期所在周的周一为基准计算间隔
+	startWeek := start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+
+	var dates []string
+	count := 0
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if s.Count > 0 && count >= s.Count {
+			break
+		}
+		matched := false
+		switch s.Freq {
+		case FreqDaily:
+			days := int(d.Sub(start).Hours() / 24)
+			matched = days%interval == 0
+		case FreqWeekly:
+			weeks := int(d.Sub(startWeek).Hours() / 24 / 7)
+			matched = weekdays[d.Weekday()] && weeks%interval == 0
+		case FreqMonthly:
+			months := (d.Year()-start.Year())*12 + int(d.Month()) - int(start.Month())
+			matched = d.Day() == start.Day() && months%interval == 0
+		}
+		if !matched {
+			continue
+		}
+		count++
+		if date := d.Format("2006-01-02"); date >= from {
+			dates = append(dates, date)
+		}
+	}
+	return dates
+}
+
+// IsFinished 判断周期预定在指定日期之后是否还会产生新的发生
+func (s *BookingSeries) IsFinished(after string) bool {
+	if s.Status == "cancelled" {
+		return true
+	}
+	if s.Until != "" && s.Until <= after {
+		return true
+	}
+	if s.Count > 0 && len(s.Occurrences(s.StartDate, after)) >= s.Count {
+		return true
+	}
+	return false
+}
+
+// RRule 生成 iCalendar 周期规则（不含 UNTIL，截止时间需按时区换算，由调用方追加）
+func (s *BookingSeries) RRule() string {
+	interval := s.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	parts := []string{"FREQ=" + strings.ToUpper(s.Freq)}
+	if interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", interval))
+	}
+	if s.Freq == FreqWeekly {
+		codes := make(map[time.Weekday]string)
+		for code, day := range weekdayCodes {
+			codes[day] = code
+		}
+		var byDay []string
+		for _, day := range s.Weekdays() {
+			byDay = append(byDay, codes[day])
+		}
+		// 与 Occurrences 一致，以周一作为每周的开始计算间隔
+		parts = append(parts, "BYDAY="+strings.Join(byDay, ","), "WKST=MO")
+	}
+	if s.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", s.Count))
+	}
+	return strings.Join(parts, ";")
+}
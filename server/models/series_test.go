@@ -0,0 +1,137 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOccurrences(t *testing.T) {
+	tests := []struct {
+		name     string
+		series   BookingSeries
+		from, to string
+		want     []string
+	}{
+		{
+			name:   "daily every other day",
+			series: BookingSeries{Freq: FreqDaily, Interval: 2, StartDate: "2025-01-06"},
+			from:   "2025-01-06", to: "2025-01-12",
+			want: []string{"2025-01-06", "2025-01-08", "2025-01-10", "2025-01-12"},
+		},
+		{
+			name:   "weekly defaults to the start weekday",
+			series: BookingSeries{Freq: FreqWeekly, StartDate: "2025-01-08"},
+			from:   "2025-01-01", to: "2025-01-31",
+			want: []string{"2025-01-08", "2025-01-15", "2025-01-22", "2025-01-29"},
+		},
+		{
+			// 开始日期所在周的周一之前的发生不计入
+			name:   "biweekly from midweek",
+			series: BookingSeries{Freq: FreqWeekly, Interval: 2, ByWeekday: "MO,WE", StartDate: "2025-01-08"},
+			from:   "2025-01-01", to: "2025-02-05",
+			want: []string{"2025-01-08", "2025-01-20", "2025-01-22", "2025-02-03", "2025-02-05"},
+		},
+		{
+			// 以周一作为每周的开始（WKST=MO）：周日与之前的周一同属一周，次日周一属于下一周
+			name:   "biweekly starting on sunday",
+			series: BookingSeries{Freq: FreqWeekly, Interval: 2, ByWeekday: "SU,MO", StartDate: "2025-01-12"},
+			from:   "2025-01-01", to: "2025-01-31",
+			want: []string{"2025-01-12", "2025-01-20", "2025-01-26"},
+		},
+		{
+			name:   "monthly on day 31 skips short months",
+			series: BookingSeries{Freq: FreqMonthly, StartDate: "2025-01-31"},
+			from:   "2025-01-01", to: "2025-08-31",
+			want: []string{"2025-01-31", "2025-03-31", "2025-05-31", "2025-07-31", "2025-08-31"},
+		},
+		{
+			name:   "quarterly",
+			series: BookingSeries{Freq: FreqMonthly, Interval: 3, StartDate: "2025-01-15"},
+			from:   "2025-01-01", to: "2025-12-31",
+			want: []string{"2025-01-15", "2025-04-15", "2025-07-15", "2025-10-15"},
+		},
+		{
+			// 从 StartDate 开始计数，from 之前的发生也占用次数
+			name:   "count is kept when from is after the start",
+			series: BookingSeries{Freq: FreqDaily, Count: 5, StartDate: "2025-01-06"},
+			from:   "2025-01-09", to: "2025-01-31",
+			want: []string{"2025-01-09", "2025-01-10"},
+		},
+		{
+			name:   "count limits weekly occurrences",
+			series: BookingSeries{Freq: FreqWeekly, ByWeekday: "MO,FR", Count: 3, StartDate: "2025-01-06"},
+			from:   "2025-01-06", to: "2025-03-31",
+			want: []string{"2025-01-06", "2025-01-10", "2025-01-13"},
+		},
+		{
+			name:   "until before to",
+			series: BookingSeries{Freq: FreqWeekly, StartDate: "2025-01-06", Until: "2025-01-20"},
+			from:   "2025-01-06", to: "2025-02-28",
+			want: []string{"2025-01-06", "2025-01-13", "2025-01-20"},
+		},
+		{
+			name:   "to before until",
+			series: BookingSeries{Freq: FreqWeekly, StartDate: "2025-01-06", Until: "2025-12-31"},
+			from:   "2025-01-06", to: "2025-01-13",
+			want: []string{"2025-01-06", "2025-01-13"},
+		},
+		{
+			name:   "invalid start date",
+			series: BookingSeries{Freq: FreqDaily, StartDate: "2025/01/06"},
+			from:   "2025-01-01", to: "2025-01-31",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.series.Occurrences(tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Occurrences(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsFinished(t *testing.T) {
+	tests := []struct {
+		name   string
+		series BookingSeries
+		after  string
+		want   bool
+	}{
+		{"cancelled", BookingSeries{Freq: FreqDaily, StartDate: "2025-01-06", Status: "cancelled"}, "2025-01-06", true},
+		{"open ended", BookingSeries{Freq: FreqDaily, StartDate: "2025-01-06"}, "2030-01-01", false},
+		{"before until", BookingSeries{Freq: FreqDaily, StartDate: "2025-01-06", Until: "2025-01-31"}, "2025-01-30", false},
+		{"at until", BookingSeries{Freq: FreqDaily, StartDate: "2025-01-06", Until: "2025-01-31"}, "2025-01-31", true},
+		{"count not reached", BookingSeries{Freq: FreqDaily, StartDate: "2025-01-06", Count: 3}, "2025-01-07", false},
+		{"count reached", BookingSeries{Freq: FreqDaily, StartDate: "2025-01-06", Count: 3}, "2025-01-08", true},
+		{"monthly count spans skipped months", BookingSeries{Freq: FreqMonthly, StartDate: "2025-01-31", Count: 3}, "2025-04-30", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.series.IsFinished(tt.after); got != tt.want {
+				t.Errorf("IsFinished(%s) = %v, want %v", tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRRule(t *testing.T) {
+	tests := []struct {
+		name   string
+		series BookingSeries
+		want   string
+	}{
+		{"daily", BookingSeries{Freq: FreqDaily, StartDate: "2025-01-06"}, "FREQ=DAILY"},
+		{"daily with count", BookingSeries{Freq: FreqDaily, Interval: 2, Count: 5, StartDate: "2025-01-06"}, "FREQ=DAILY;INTERVAL=2;COUNT=5"},
+		{"weekly defaults to the start weekday", BookingSeries{Freq: FreqWeekly, StartDate: "2025-01-08"}, "FREQ=WEEKLY;BYDAY=WE;WKST=MO"},
+		{"biweekly", BookingSeries{Freq: FreqWeekly, Interval: 2, ByWeekday: "MO,WE", Count: 10, StartDate: "2025-01-06"}, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;WKST=MO;COUNT=10"},
+		{"monthly", BookingSeries{Freq: FreqMonthly, Interval: 3, StartDate: "2025-01-31"}, "FREQ=MONTHLY;INTERVAL=3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.series.RRule(); got != tt.want {
+				t.Errorf("RRule = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
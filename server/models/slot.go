@@ -0,0 +1,27 @@
+package models
+
+import (
+	"roomly/timeslot"
+)
+
+// HoldingStatuses 占用时间段的预定状态：已确认和待审批的预定都会占用时间段
+var HoldingStatuses = []string{"active", "pending"}
+
+// OccupiedSlots 计算预定需要占用的时间单元
+func OccupiedSlots(booking *Booking) []BookingSlot {
+	window, err := timeslot.ParseWindow(booking.StartTime, booking.EndTime)
+	if err != nil {
+		return nil
+	}
+
+	var slots []BookingSlot
+	for _, minute := range window.Units() {
+		slots = append(slots, BookingSlot{
+			BookingID: booking.ID,
+			RoomID:    booking.RoomID,
+			Date:      booking.Date,
+			Minute:    minute,
+		})
+	}
+	return slots
+}
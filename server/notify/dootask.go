@@ -0,0 +1,32 @@
+package notify
+
+import (
+	"errors"
+
+	"roomly/models"
+)
+
+func init() {
+	Register(DooTask{})
+}
+
+// DooTask 通过 DooTask 机器人发送通知
+type DooTask struct{}
+
+// Channel 实现 Notifier
+func (DooTask) Channel() string {
+	return ChannelDooTask
+}
+
+// Send 实现 Notifier，没有 token 时使用 DOOTASK_BOT_TOKEN
+func (DooTask) Send(recipient Recipient, message Message) error {
+	token := message.Token
+	if token == "" {
+		token = models.SystemToken()
+	}
+	if token == "" {
+		return errors.New("DOOTASK_BOT_TOKEN is not configured")
+	}
+	client := models.NewDooTaskClient(token)
+	return client.SendBotMessage(recipient.Userid, message.Text)
+}
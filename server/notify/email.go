@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+func init() {
+	// 配置了 SMTP_HOST 时才启用邮件渠道
+	if email, ok := EmailFromEnv(); ok {
+		Register(email)
+	}
+}
+
+// Email 通过 SMTP 发送邮件通知
+type Email struct {
+	Host     string
+	Port     string
+	Username string // 为空时不认证
+	Password string
+	From     string
+}
+
+// EmailFromEnv 从环境变量 SMTP_HOST、SMTP_PORT（默认25）、SMTP_USERNAME、SMTP_PASSWORD、SMTP_FROM 读取邮件配置，
+// 没有配置 SMTP_HOST 时返回 false
+func EmailFromEnv() (Email, bool) {
+	email := Email{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     os.Getenv("SMTP_PORT"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+	}
+	if email.Host == "" {
+		return email, false
+	}
+	if email.Port == "" {
+		email.Port = "25"
+	}
+	if email.From == "" {
+		email.From = email.Username
+	}
+	return email, true
+}
+
+// Channel 实现 Notifier
+func (e Email) Channel() string {
+	return ChannelEmail
+}
+
+// Send 实现 Notifier，正文为纯文本
+func (e Email) Send(recipient Recipient, message Message) error {
+	if recipient.Email == "" {
+		return errors.New("Recipient has no email address")
+	}
+	if strings.ContainsAny(recipient.Email, "\r\n") {
+		return errors.New("Invalid email address")
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", e.From)
+	fmt.Fprintf(&body, "To: %s\r\n", recipient.Email)
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	body.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	body.WriteString("\r\n")
+	body.WriteString(strings.ReplaceAll(message.Text, "\n", "\r\n"))
+	body.WriteString("\r\n")
+
+	var auth smtp.Auth
+	if e.Username != "" {
+		auth = smtp.PlainAuth("", e.Username, e.Password, e.Host)
+	}
+	return smtp.SendMail(e.Host+":"+e.Port, auth, e.From, []string{recipient.Email}, []byte(body.String()))
+}
@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// SMTP 服务收到的邮件
+type mail struct {
+	from string
+	to   []string
+	data string // 原始内容，保留 CRLF
+}
+
+// 启动只实现发送邮件所需命令的 SMTP 服务，收到的邮件写入返回的 channel
+func startSMTPServer(t *testing.T) (host, port string, mails <-chan mail) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan mail, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		text := textproto.NewConn(conn)
+		text.PrintfLine("220 localhost ESMTP")
+		var current mail
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			switch command, arg, _ := strings.Cut(line, " "); strings.ToUpper(command) {
+			case "EHLO", "HELO":
+				text.PrintfLine("250 localhost")
+			case "MAIL":
+				current.from = strings.TrimPrefix(arg, "FROM:")
+				text.PrintfLine("250 OK")
+			case "RCPT":
+				current.to = append(current.to, strings.TrimPrefix(arg, "TO:"))
+				text.PrintfLine("250 OK")
+			case "DATA":
+				text.PrintfLine("354 Go ahead")
+				var data strings.Builder
+				for {
+					raw, err := text.R.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if raw == ".\r\n" {
+						break
+					}
+					data.WriteString(raw)
+				}
+				current.data = data.String()
+				text.PrintfLine("250 OK")
+			case "QUIT":
+				text.PrintfLine("221 Bye")
+				received <- current
+				return
+			default:
+				text.PrintfLine("502 Not implemented")
+			}
+		}
+	}()
+
+	host, port, _ = net.SplitHostPort(listener.Addr().String())
+	return host, port, received
+}
+
+func TestEmailSend(t *testing.T) {
+	host, port, mails := startSMTPServer(t)
+	email := Email{Host: host, Port: port, From: "roomly@example.com"}
+
+	err := email.Send(Recipient{Userid: 1, Email: "alice@example.com"}, Message{
+		Event:    "remind",
+		Audience: "attendee",
+		Subject:  "会议提醒",
+		Text:     "## 📢  会议提醒\n- **会议室**：多功能会议室A",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := <-mails
+	if got.from != "<roomly@example.com>" {
+		t.Errorf("MAIL FROM = %q", got.from)
+	}
+	if len(got.to) != 1 || got.to[0] != "<alice@example.com>" {
+		t.Errorf("RCPT TO = %q", got.to)
+	}
+	for _, header := range []string{
+		"From: roomly@example.com\r\n",
+		"To: alice@example.com\r\n",
+		"Subject: =?utf-8?q?=E4=BC=9A=E8=AE=AE=E6=8F=90=E9=86=92?=\r\n",
+		"Content-Type: text/plain; charset=utf-8\r\n",
+	} {
+		if !strings.Contains(got.data, header) {
+			t.Errorf("missing header %q in\n%s", header, got.data)
+		}
+	}
+	// 正文的换行转换为 CRLF
+	if !strings.HasSuffix(got.data, "\r\n\r\n## 📢  会议提醒\r\n- **会议室**：多功能会议室A\r\n") {
+		t.Errorf("unexpected body in\n%s", got.data)
+	}
+}
+
+func TestEmailSendRejectsInvalidAddress(t *testing.T) {
+	// 地址不合法时不连接 SMTP 服务
+	email := Email{Host: "127.0.0.1", Port: "0", From: "roomly@example.com"}
+	for _, address := range []string{"", "alice@example.com\r\nBcc: mallory@example.com"} {
+		if err := email.Send(Recipient{Userid: 1, Email: address}, Message{Subject: "会议提醒", Text: "会议提醒"}); err == nil {
+			t.Errorf("Send to %q succeeded", address)
+		}
+	}
+}
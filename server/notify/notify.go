@@ -0,0 +1,138 @@
+// Package notify 定义会议通知的领域事件和发送渠道。业务代码只发布 Event，不关心通过哪些渠道发送；
+// 通知发件箱按接收人的渠道偏好选择 Notifier 投递。
+package notify
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 发送渠道
+const (
+	ChannelDooTask = "dootask"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// Event 会议通知事件，Type 为事件类型，对应消息模板；UserIDs 接收参会人员版本、
+// OrganizerIDs 接收预定人版本、AdminIDs 接收会议室管理员版本的消息
+type Event struct {
+	Type         string
+	UserIDs      []int
+	OrganizerIDs []int
+	AdminIDs     []int
+	Token        string // 发送消息使用的 token，为空时使用 DOOTASK_BOT_TOKEN
+	Date         string
+	StartTime    string
+	EndTime      string
+	RoomName     string
+	Reason       string
+	Attendees    string
+	CalendarURL  string      // 会议日历文件的地址，可为空
+	Series       *Recurrence // 周期预定的规则，不为空时消息中的会议日期按语言描述该规则
+	Changes      []Change    // 预定的变更内容，change 事件使用
+	Content      []string
+}
+
+// Change 预定的一项变更，Field 为 room、date、time、reason、privacy 或 attendees，
+// privacy 的取值为 true 或 false，字段名称和取值由消息模板按语言显示
+type Change struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Recurrence 周期预定的规则，由消息模板按语言描述
+type Recurrence struct {
+	Freq      string `json:"freq"` // daily、weekly 或 monthly
+	Interval  int    `json:"interval"`
+	Weekdays  []int  `json:"weekdays,omitempty"` // 每周重复的星期，0 为周日
+	Day       int    `json:"day,omitempty"`      // 每月重复的日期
+	StartDate string `json:"start_date"`
+	Until     string `json:"until,omitempty"`
+	Count     int    `json:"count,omitempty"`
+	From      string `json:"from,omitempty"` // 只涉及该日期起的后续会议时为该日期
+}
+
+// Recipient 通知接收人及其在各渠道的地址
+type Recipient struct {
+	Userid     uint   // dootask 用户 ID
+	Email      string // 邮件地址，email 渠道使用
+	WebhookURL string // 回调地址，webhook 渠道使用
+	Locale     string // 消息语言
+}
+
+// Message 渲染后的通知
+type Message struct {
+	Event    string // 事件类型
+	Audience string // attendee、organizer 或 admin
+	Token    string // 发送 DooTask 消息使用的 token
+	Subject  string
+	Text     string // Markdown 格式的正文
+}
+
+// Notifier 通知发送渠道
+type Notifier interface {
+	// Channel 渠道名称
+	Channel() string
+	// Send 发送一条通知，返回错误时由发件箱重试
+	Send(recipient Recipient, message Message) error
+}
+
+var (
+	mu        sync.RWMutex
+	notifiers = make(map[string]Notifier)
+)
+
+// Register 注册发送渠道，同名渠道会被替换
+func Register(notifier Notifier) {
+	mu.Lock()
+	defer mu.Unlock()
+	notifiers[notifier.Channel()] = notifier
+}
+
+// Lookup 按名称获取已注册的发送渠道
+func Lookup(channel string) (Notifier, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	notifier, ok := notifiers[channel]
+	return notifier, ok
+}
+
+// Channels 已注册的发送渠道名称
+func Channels() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	var channels []string
+	for channel := range notifiers {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// ParseChannels 解析逗号分隔的渠道列表，去掉重复项，渠道必须已注册
+func ParseChannels(value string) ([]string, error) {
+	seen := make(map[string]bool)
+	var channels []string
+	for _, channel := range strings.Split(value, ",") {
+		channel = strings.TrimSpace(channel)
+		if channel == "" || seen[channel] {
+			continue
+		}
+		if _, ok := Lookup(channel); !ok {
+			return nil, fmt.Errorf("Channel %s is not available", channel)
+		}
+		seen[channel] = true
+		channels = append(channels, channel)
+	}
+	return channels, nil
+}
+
+// Subject 取 Markdown 正文第一行标题作为通知主题
+func Subject(text string) string {
+	line := strings.TrimSpace(strings.SplitN(text, "\n", 2)[0])
+	return strings.Join(strings.Fields(strings.TrimLeft(line, "# ")), " ")
+}
@@ -0,0 +1,135 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+func init() {
+	Register(Webhook{Client: NewWebhookClient(10 * time.Second)})
+}
+
+// ErrBlockedAddress 回调地址指向本机、内网或链路本地地址
+var ErrBlockedAddress = errors.New("Webhook URL must not point to a loopback, private or link-local address")
+
+// 除标准库判断的内网地址外，同样不允许回调的地址段
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // 本网络
+	netip.MustParsePrefix("100.64.0.0/10"), // 运营商级 NAT
+}
+
+// Webhook 将通知以 JSON POST 到接收人配置的回调地址
+type Webhook struct {
+	Client *http.Client
+}
+
+// NewWebhookClient 发送回调使用的 HTTP 客户端：回调地址由会员填写，
+// 每次建立连接前检查实际连接的地址，重定向和重新解析域名也不能访问本机或内网
+func NewWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || IsBlockedIP(ip) {
+				return ErrBlockedAddress
+			}
+			return nil
+		},
+	}
+	// 不使用代理，否则检查的是代理的地址
+	return &http.Client{Timeout: timeout, Transport: &http.Transport{Proxy: nil, DialContext: dialer.DialContext}}
+}
+
+// IsBlockedIP 判断是否为不允许回调的地址：本机、内网、链路本地、未指定和组播地址
+func IsBlockedIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return true
+	}
+	addr = addr.Unmap()
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckWebhookURL 校验会员填写的回调地址：必须是 http 或 https 地址，且域名解析出的地址都允许回调
+func CheckWebhookURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return errors.New("webhook_url must be an http or https URL")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, parsed.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return errors.New("webhook_url host cannot be resolved")
+	}
+	for _, addr := range addrs {
+		if IsBlockedIP(addr.IP) {
+			return ErrBlockedAddress
+		}
+	}
+	return nil
+}
+
+// 回调请求的内容
+type webhookPayload struct {
+	Event    string    `json:"event"`
+	Audience string    `json:"audience"`
+	Userid   uint      `json:"userid"`
+	Subject  string    `json:"subject"`
+	Text     string    `json:"text"`
+	SentAt   time.Time `json:"sent_at"`
+}
+
+// Channel 实现 Notifier
+func (w Webhook) Channel() string {
+	return ChannelWebhook
+}
+
+// Send 实现 Notifier，回调地址返回非 2xx 状态码时视为发送失败
+func (w Webhook) Send(recipient Recipient, message Message) error {
+	if recipient.WebhookURL == "" {
+		return errors.New("Recipient has no webhook URL")
+	}
+	data, err := json.Marshal(webhookPayload{
+		Event:    message.Event,
+		Audience: message.Audience,
+		Userid:   recipient.Userid,
+		Subject:  message.Subject,
+		Text:     message.Text,
+		SentAt:   time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := w.Client.Post(recipient.WebhookURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
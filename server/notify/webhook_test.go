@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIsBlockedIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.10", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"0.0.0.0", true},
+		{"100.64.0.1", true},
+		{"::ffff:127.0.0.1", true},
+		{"224.0.0.1", true},
+		{"93.184.216.34", false},
+		{"2606:4700:4700::1111", false},
+	}
+	for _, tt := range tests {
+		if got := IsBlockedIP(net.ParseIP(tt.ip)); got != tt.blocked {
+			t.Errorf("IsBlockedIP(%s) = %v, want %v", tt.ip, got, tt.blocked)
+		}
+	}
+}
+
+func TestCheckWebhookURL(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://93.184.216.34/hooks/roomly", true},
+		{"http://93.184.216.34:8080/", true},
+		{"ftp://93.184.216.34/", false},
+		{"/hooks/roomly", false},
+		{"http://127.0.0.1:8080/", false},
+		{"http://localhost/", false},
+		{"http://[::1]/", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://10.0.0.5/", false},
+	}
+	for _, tt := range tests {
+		if err := CheckWebhookURL(tt.url); (err == nil) != tt.ok {
+			t.Errorf("CheckWebhookURL(%q) = %v, want ok %v", tt.url, err, tt.ok)
+		}
+	}
+}
+
+// 即使保存时地址可用，发送时连接到本机或内网地址也会被拒绝
+func TestWebhookSendRejectsBlockedAddress(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	webhook := Webhook{Client: NewWebhookClient(time.Second)}
+	err := webhook.Send(Recipient{Userid: 1, WebhookURL: server.URL}, Message{Event: "remind", Subject: "会议提醒", Text: "会议提醒"})
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("Send = %v, want %v", err, ErrBlockedAddress)
+	}
+	if requests != 0 {
+		t.Errorf("server received %d requests", requests)
+	}
+}
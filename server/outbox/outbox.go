@@ -0,0 +1,286 @@
+// Package outbox 通知发件箱：通知事件与业务数据在同一事务中写入 notifications 表，提交后由投递任务发送。
+// 每个接收人按其渠道偏好在每个渠道各写入一条通知；发送失败按指数退避重试，
+// 超过最大尝试次数后标记为 dead，由管理员检查后手动重试。
+package outbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"roomly/database"
+	"roomly/message"
+	"roomly/models"
+	"roomly/notify"
+
+	"gorm.io/gorm"
+)
+
+// 通知状态
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusDead    = "dead"
+)
+
+// MaxAttempts 最多尝试投递的次数，超过后标记为 dead
+const MaxAttempts = 8
+
+// 重试间隔从 baseBackoff 开始每次失败翻倍，最长为 maxBackoff
+const (
+	baseBackoff = 30 * time.Second
+	maxBackoff  = time.Hour
+)
+
+// 投递中的通知在该时间内不会被其他投递任务领取，进程中断时超时后自动重新投递
+const leaseDuration = 2 * time.Minute
+
+// 每次投递最多处理的通知数
+const batchSize = 100
+
+// 通知事件中与接收人无关的消息参数
+type payload struct {
+	Date        string             `json:"date"`
+	StartTime   string             `json:"start_time"`
+	EndTime     string             `json:"end_time"`
+	RoomName    string             `json:"room_name"`
+	Reason      string             `json:"reason"`
+	Attendees   string             `json:"attendees"`
+	CalendarURL string             `json:"calendar_url,omitempty"`
+	Series      *notify.Recurrence `json:"series,omitempty"`
+	Changes     []notify.Change    `json:"changes,omitempty"`
+	Content     []string           `json:"content,omitempty"`
+}
+
+// Publish 在事务 tx 中发布通知事件：为每个接收人在其选择的每个渠道写入一条待发送的通知，
+// 重复的接收人只写入一次，不是会员或没有设置渠道偏好的接收人通过 DooTask 接收
+func Publish(tx *gorm.DB, event notify.Event) error {
+	data, err := json.Marshal(payload{
+		Date:        event.Date,
+		StartTime:   event.StartTime,
+		EndTime:     event.EndTime,
+		RoomName:    event.RoomName,
+		Reason:      event.Reason,
+		Attendees:   event.Attendees,
+		CalendarURL: event.CalendarURL,
+		Series:      event.Series,
+		Changes:     event.Changes,
+		Content:     event.Content,
+	})
+	if err != nil {
+		return err
+	}
+
+	var userids []uint
+	var ids []int
+	ids = append(ids, event.UserIDs...)
+	ids = append(ids, event.OrganizerIDs...)
+	ids = append(ids, event.AdminIDs...)
+	for _, id := range ids {
+		if id > 0 {
+			userids = append(userids, uint(id))
+		}
+	}
+	if len(userids) == 0 {
+		return nil
+	}
+	channels, err := recipientChannels(tx, userids)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var notifications []models.Notification
+	add := func(ids []int, audience string) {
+		seen := make(map[int]bool)
+		for _, id := range ids {
+			if id <= 0 || seen[id] {
+				continue
+			}
+			seen[id] = true
+			for _, channel := range channels[uint(id)] {
+				notifications = append(notifications, models.Notification{
+					Userid:        uint(id),
+					Audience:      audience,
+					Channel:       channel,
+					MsgType:       event.Type,
+					Payload:       string(data),
+					Token:         event.Token,
+					Status:        StatusPending,
+					NextAttemptAt: now,
+				})
+			}
+		}
+	}
+	add(event.UserIDs, models.AudienceAttendee)
+	add(event.OrganizerIDs, models.AudienceOrganizer)
+	add(event.AdminIDs, models.AudienceAdmin)
+	if len(notifications) == 0 {
+		return nil
+	}
+	return tx.Create(&notifications).Error
+}
+
+// 按 dootask 用户 ID 加载接收通知的渠道，未设置或设置的渠道都不可用时使用 DooTask
+func recipientChannels(db *gorm.DB, userids []uint) (map[uint][]string, error) {
+	var rows []struct {
+		DootaskID uint
+		Channels  string
+	}
+	if err := db.Table("member_preferences").
+		Select("members.dootask_id, member_preferences.channels").
+		Joins("JOIN members ON members.id = member_preferences.member_id").
+		Where("members.dootask_id IN ?", userids).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	channels := make(map[uint][]string)
+	for _, row := range rows {
+		for _, channel := range strings.Split(row.Channels, ",") {
+			if _, ok := notify.Lookup(channel); ok {
+				channels[row.DootaskID] = append(channels[row.DootaskID], channel)
+			}
+		}
+	}
+	for _, userid := range userids {
+		if len(channels[userid]) == 0 {
+			channels[userid] = []string{notify.ChannelDooTask}
+		}
+	}
+	return channels, nil
+}
+
+// Deliver 发送所有到期的待发送通知；先领取再发送，可以与其他投递任务并发运行
+func Deliver() {
+	now := time.Now()
+	var due []models.Notification
+	if err := database.DB.Where("status = ? AND next_attempt_at <= ?", StatusPending, now).
+		Order("id asc").Limit(batchSize).Find(&due).Error; err != nil {
+		fmt.Printf("加载待发送通知失败: %v\n", err)
+		return
+	}
+
+	for i := range due {
+		notification := &due[i]
+
+		// 领取通知：推迟下次尝试时间，其他投递任务不会重复发送
+		result := database.DB.Model(&models.Notification{}).
+			Where("id = ? AND status = ? AND next_attempt_at <= ?", notification.ID, StatusPending, now).
+			Updates(map[string]interface{}{
+				"attempts":        gorm.Expr("attempts + 1"),
+				"next_attempt_at": time.Now().Add(leaseDuration),
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			continue
+		}
+		notification.Attempts++
+
+		if err := send(notification); err != nil {
+			updates := map[string]interface{}{"last_error": err.Error()}
+			if notification.Attempts >= MaxAttempts {
+				updates["status"] = StatusDead
+			} else {
+				updates["next_attempt_at"] = time.Now().Add(Backoff(notification.Attempts))
+			}
+			database.DB.Model(notification).Updates(updates)
+			fmt.Printf("通过%s发送通知%d给用户%d失败（第%d次）: %v\n", notification.Channel, notification.ID, notification.Userid, notification.Attempts, err)
+			continue
+		}
+
+		sentAt := time.Now()
+		database.DB.Model(notification).Updates(map[string]interface{}{"status": StatusSent, "sent_at": sentAt, "last_error": ""})
+	}
+}
+
+// Backoff 第 attempts 次投递失败后的重试间隔
+func Backoff(attempts int) time.Duration {
+	backoff := baseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return backoff
+}
+
+// Retry 将通知重新置为待发送并清零尝试次数，已发送的通知不能重试
+func Retry(db *gorm.DB, notification *models.Notification) error {
+	if notification.Status == StatusSent {
+		return errors.New("Notification has already been sent")
+	}
+	notification.Status = StatusPending
+	notification.Attempts = 0
+	notification.NextAttemptAt = time.Now()
+	return db.Model(notification).Updates(map[string]interface{}{
+		"status":          notification.Status,
+		"attempts":        notification.Attempts,
+		"next_attempt_at": notification.NextAttemptAt,
+	}).Error
+}
+
+// PruneSent 删除发送时间早于 before 的已发送通知
+func PruneSent(before time.Time) {
+	database.DB.Where("status = ? AND sent_at < ?", StatusSent, before).Delete(&models.Notification{})
+}
+
+// 按接收人的语言渲染消息模板，通过通知的渠道发送单条通知；接收人的地址和语言按发送时的通知偏好
+func send(notification *models.Notification) error {
+	notifier, ok := notify.Lookup(notification.Channel)
+	if !ok {
+		return fmt.Errorf("Channel %s is not available", notification.Channel)
+	}
+	var data payload
+	if err := json.Unmarshal([]byte(notification.Payload), &data); err != nil {
+		return err
+	}
+
+	token := notification.Token
+	if token == "" {
+		token = models.SystemToken()
+	}
+	to := recipient(notification.Userid)
+	text, err := message.Render(notification.MsgType, notification.Audience, to.Locale, message.Data{
+		Sender:      models.SenderNickname(token),
+		Date:        data.Date,
+		StartTime:   data.StartTime,
+		EndTime:     data.EndTime,
+		RoomName:    data.RoomName,
+		Reason:      data.Reason,
+		Attendees:   data.Attendees,
+		CalendarURL: data.CalendarURL,
+		Series:      data.Series,
+		Changes:     data.Changes,
+		Content:     data.Content,
+	})
+	if err != nil {
+		return err
+	}
+	msg := notify.Message{
+		Event:    notification.MsgType,
+		Audience: notification.Audience,
+		Token:    token,
+		Subject:  notify.Subject(text),
+		Text:     text,
+	}
+	return notifier.Send(to, msg)
+}
+
+// 加载接收人在各渠道的地址和消息语言，不是会员或未设置时只有 dootask 用户 ID，使用默认语言
+func recipient(userid uint) notify.Recipient {
+	recipient := notify.Recipient{Userid: userid, Locale: message.DefaultLocale}
+	var preference models.MemberPreference
+	err := database.DB.Joins("JOIN members ON members.id = member_preferences.member_id").
+		Where("members.dootask_id = ?", userid).First(&preference).Error
+	if err == nil {
+		recipient.Email = preference.Email
+		recipient.WebhookURL = preference.WebhookURL
+		if preference.Locale != "" {
+			recipient.Locale = preference.Locale
+		}
+	}
+	return recipient
+}
@@ -0,0 +1,144 @@
+// Package policy 定义接口访问的角色与权限规则，不依赖 HTTP 层，便于单独测试
+package policy
+
+import (
+	"errors"
+
+	"roomly/models"
+)
+
+// Role 会员角色，数值越大权限越高
+type Role int
+
+const (
+	RoleMember Role = iota
+	RoleRoomAdmin
+	RoleAdmin
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleAdmin:
+		return "admin"
+	case RoleRoomAdmin:
+		return "room_admin"
+	default:
+		return "member"
+	}
+}
+
+var (
+	// ErrUnauthenticated 未登录
+	ErrUnauthenticated = errors.New("authentication required")
+	// ErrForbidden 已登录但权限不足
+	ErrForbidden = errors.New("permission denied")
+)
+
+// RoleOf 获取会员的最高角色
+func RoleOf(member *models.Member) Role {
+	switch {
+	case member.IsAdmin:
+		return RoleAdmin
+	case member.IsRoomAdmin:
+		return RoleRoomAdmin
+	default:
+		return RoleMember
+	}
+}
+
+// RequireRole 检查会员是否具备指定角色，管理员拥有会议室管理员的全部权限
+func RequireRole(member *models.Member, role Role) error {
+	if member == nil {
+		return ErrUnauthenticated
+	}
+	if RoleOf(member) < role {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// ManagesRoom 判断会员是否为指定会议室的管理员，依赖已加载的 ManagedRooms
+func ManagesRoom(member *models.Member, roomID uint) bool {
+	for _, room := range member.ManagedRooms {
+		if room.ID == roomID {
+			return true
+		}
+	}
+	return false
+}
+
+// CanManageRoom 管理员可以管理所有会议室，会议室管理员只能管理分配给自己的会议室
+func CanManageRoom(member *models.Member, roomID uint) error {
+	if member == nil {
+		return ErrUnauthenticated
+	}
+	if member.IsAdmin || ManagesRoom(member, roomID) {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// CanManageBooking 预定人、该会议室的管理员和管理员可以取消或修改预定
+func CanManageBooking(member *models.Member, booking *models.Booking) error {
+	if member == nil {
+		return ErrUnauthenticated
+	}
+	if booking.MemberID == member.ID {
+		return nil
+	}
+	return CanManageRoom(member, booking.RoomID)
+}
+
+// ManagedRoomIDs 返回会员可管理的会议室 ID，仅管理员返回 nil 表示不限；未登录时返回空列表
+func ManagedRoomIDs(member *models.Member) []uint {
+	if member == nil {
+		return []uint{}
+	}
+	if member.IsAdmin {
+		return nil
+	}
+	ids := []uint{}
+	for _, room := range member.ManagedRooms {
+		ids = append(ids, room.ID)
+	}
+	return ids
+}
+
+// CanCheckIn 预定人和参会人员可以签到（需要已加载 BookingUsers）
+func CanCheckIn(member *models.Member, booking *models.Booking) error {
+	if member == nil {
+		return ErrUnauthenticated
+	}
+	if booking.MemberID == member.ID {
+		return nil
+	}
+	for _, user := range booking.BookingUsers {
+		if user.Userid == member.DootaskID {
+			return nil
+		}
+	}
+	return ErrForbidden
+}
+
+// CanViewBookingDetails 非私密会议所有人可见；私密会议的详情仅预定人、参会人员、
+// 该会议室的管理员和管理员可见（需要已加载 BookingUsers）
+func CanViewBookingDetails(member *models.Member, booking *models.Booking) error {
+	if !booking.IsPrivate {
+		return nil
+	}
+	if err := CanCheckIn(member, booking); err == nil {
+		return nil
+	}
+	return CanManageRoom(member, booking.RoomID)
+}
+
+// CanViewMember 会员本人和管理员可以查看会员的私有数据（如预定记录）
+func CanViewMember(member *models.Member, memberID uint) error {
+	if member == nil {
+		return ErrUnauthenticated
+	}
+	if member.ID == memberID {
+		return nil
+	}
+	return RequireRole(member, RoleRoomAdmin)
+}
@@ -0,0 +1,176 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+
+	"roomly/models"
+)
+
+var (
+	admin     = &models.Member{ID: 1, IsAdmin: true}
+	roomAdmin = &models.Member{ID: 2, IsRoomAdmin: true, ManagedRooms: []models.Room{{ID: 10}, {ID: 11}}}
+	organizer = &models.Member{ID: 3, DootaskID: 300}
+	attendee  = &models.Member{ID: 4, DootaskID: 400}
+	outsider  = &models.Member{ID: 5, DootaskID: 500}
+)
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name   string
+		member *models.Member
+		role   Role
+		want   error
+	}{
+		{"anonymous", nil, RoleMember, ErrUnauthenticated},
+		{"member as member", organizer, RoleMember, nil},
+		{"member as room admin", organizer, RoleRoomAdmin, ErrForbidden},
+		{"room admin as room admin", roomAdmin, RoleRoomAdmin, nil},
+		{"room admin as admin", roomAdmin, RoleAdmin, ErrForbidden},
+		{"admin as room admin", admin, RoleRoomAdmin, nil},
+		{"admin as admin", admin, RoleAdmin, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := RequireRole(tt.member, tt.role); err != tt.want {
+				t.Errorf("RequireRole = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanManageRoom(t *testing.T) {
+	tests := []struct {
+		name   string
+		member *models.Member
+		roomID uint
+		want   error
+	}{
+		{"anonymous", nil, 10, ErrUnauthenticated},
+		{"admin", admin, 99, nil},
+		{"room admin of the room", roomAdmin, 11, nil},
+		{"room admin of another room", roomAdmin, 12, ErrForbidden},
+		{"member", organizer, 10, ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CanManageRoom(tt.member, tt.roomID); err != tt.want {
+				t.Errorf("CanManageRoom = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanManageBooking(t *testing.T) {
+	booking := &models.Booking{MemberID: organizer.ID, RoomID: 10}
+	tests := []struct {
+		name   string
+		member *models.Member
+		want   error
+	}{
+		{"anonymous", nil, ErrUnauthenticated},
+		{"organizer", organizer, nil},
+		{"room admin", roomAdmin, nil},
+		{"admin", admin, nil},
+		{"other member", outsider, ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CanManageBooking(tt.member, booking); err != tt.want {
+				t.Errorf("CanManageBooking = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestManagedRoomIDs(t *testing.T) {
+	tests := []struct {
+		name   string
+		member *models.Member
+		want   []uint
+	}{
+		// 未登录时不能管理任何会议室，不能返回表示不限的 nil
+		{"anonymous", nil, []uint{}},
+		{"admin", admin, nil},
+		{"room admin", roomAdmin, []uint{10, 11}},
+		{"member", organizer, []uint{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ManagedRoomIDs(tt.member); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ManagedRoomIDs = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanCheckIn(t *testing.T) {
+	booking := &models.Booking{MemberID: organizer.ID, RoomID: 10, BookingUsers: []models.BookingUser{{Userid: attendee.DootaskID}}}
+	tests := []struct {
+		name   string
+		member *models.Member
+		want   error
+	}{
+		{"anonymous", nil, ErrUnauthenticated},
+		{"organizer", organizer, nil},
+		{"attendee", attendee, nil},
+		{"room admin", roomAdmin, ErrForbidden},
+		{"other member", outsider, ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CanCheckIn(tt.member, booking); err != tt.want {
+				t.Errorf("CanCheckIn = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanViewBookingDetails(t *testing.T) {
+	public := &models.Booking{MemberID: organizer.ID, RoomID: 10}
+	private := &models.Booking{MemberID: organizer.ID, RoomID: 10, IsPrivate: true, BookingUsers: []models.BookingUser{{Userid: attendee.DootaskID}}}
+	tests := []struct {
+		name    string
+		member  *models.Member
+		booking *models.Booking
+		want    error
+	}{
+		{"public to anonymous", nil, public, nil},
+		{"public to other member", outsider, public, nil},
+		{"private to anonymous", nil, private, ErrUnauthenticated},
+		{"private to organizer", organizer, private, nil},
+		{"private to attendee", attendee, private, nil},
+		{"private to room admin", roomAdmin, private, nil},
+		{"private to admin", admin, private, nil},
+		{"private to other member", outsider, private, ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CanViewBookingDetails(tt.member, tt.booking); err != tt.want {
+				t.Errorf("CanViewBookingDetails = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanViewMember(t *testing.T) {
+	tests := []struct {
+		name     string
+		member   *models.Member
+		memberID uint
+		want     error
+	}{
+		{"anonymous", nil, organizer.ID, ErrUnauthenticated},
+		{"self", organizer, organizer.ID, nil},
+		{"other member", outsider, organizer.ID, ErrForbidden},
+		{"room admin", roomAdmin, organizer.ID, nil},
+		{"admin", admin, organizer.ID, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CanViewMember(tt.member, tt.memberID); err != tt.want {
+				t.Errorf("CanViewMember = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Package quota 评估会员的预定配额：有效预定数、每周使用时长、单次时长和最短提前预定时间。
+// 所有限制为 0 表示不限，使用量由调用方统计后传入，本包不依赖数据库。
+package quota
+
+import (
+	"fmt"
+	"time"
+)
+
+// 配额规则名称，违反时返回给前端用于提示
+const (
+	RuleMaxActiveBookings = "max_active_bookings"
+	RuleMaxWeeklyMinutes  = "max_weekly_minutes"
+	RuleMaxDuration       = "max_duration"
+	RuleMinLeadMinutes    = "min_lead_minutes"
+)
+
+// 适用对象
+const (
+	AudienceMember = "member" // 普通会员
+	AudienceAdmin  = "admin"  // 该会议室的管理员和管理员
+)
+
+// IsValidAudience 判断适用对象是否合法
+func IsValidAudience(audience string) bool {
+	return audience == AudienceMember || audience == AudienceAdmin
+}
+
+// Limits 一条配额策略的各项限制
+type Limits struct {
+	MaxActiveBookings int // 最多同时持有的未结束预定数
+	MaxWeeklyMinutes  int // 每个会议室每周（周一至周日）最多预定的分钟数
+	MaxDuration       int // 单次预定最长分钟数
+	MinLeadMinutes    int // 至少提前多少分钟预定
+}
+
+// Validate 检查限制是否合法
+func (l Limits) Validate() error {
+	if l.MaxActiveBookings < 0 || l.MaxWeeklyMinutes < 0 || l.MaxDuration < 0 || l.MinLeadMinutes < 0 {
+		return fmt.Errorf("quota limits must not be negative")
+	}
+	return nil
+}
+
+// Request 待检查的预定
+type Request struct {
+	Duration int           // 预定时长（分钟）
+	Lead     time.Duration // 距离会议开始的时间
+}
+
+// Usage 会员已有的使用量，不包含待检查的预定
+type Usage struct {
+	ActiveBookings int // 未结束的预定数
+	WeeklyMinutes  int // 同一会议室同一周已预定的分钟数
+}
+
+// Violation 违反的配额规则
+type Violation struct {
+	Rule   string // 规则名称
+	Limit  int    // 策略限制
+	Actual int    // 加上本次预定后的使用量
+}
+
+func (v *Violation) Error() string {
+	switch v.Rule {
+	case RuleMaxActiveBookings:
+		return fmt.Sprintf("Booking quota exceeded: at most %d active bookings are allowed", v.Limit)
+	case RuleMaxWeeklyMinutes:
+		return fmt.Sprintf("Booking quota exceeded: at most %d minutes per week are allowed in this room", v.Limit)
+	case RuleMaxDuration:
+		return fmt.Sprintf("Booking quota exceeded: a single booking may last at most %d minutes", v.Limit)
+	case RuleMinLeadMinutes:
+		return fmt.Sprintf("Booking quota exceeded: bookings must be made at least %d minutes in advance", v.Limit)
+	}
+	return "Booking quota exceeded"
+}
+
+// Check 按单次时长、提前时间、有效预定数、每周时长的顺序检查，返回第一个违反的规则
+func Check(limits Limits, request Request, usage Usage) *Violation {
+	if limits.MaxDuration > 0 && request.Duration > limits.MaxDuration {
+		return &Violation{Rule: RuleMaxDuration, Limit: limits.MaxDuration, Actual: request.Duration}
+	}
+	if lead := int(request.Lead / time.Minute); limits.MinLeadMinutes > 0 && lead < limits.MinLeadMinutes {
+		return &Violation{Rule: RuleMinLeadMinutes, Limit: limits.MinLeadMinutes, Actual: lead}
+	}
+	if limits.MaxActiveBookings > 0 && usage.ActiveBookings+1 > limits.MaxActiveBookings {
+		return &Violation{Rule: RuleMaxActiveBookings, Limit: limits.MaxActiveBookings, Actual: usage.ActiveBookings + 1}
+	}
+	if limits.MaxWeeklyMinutes > 0 && usage.WeeklyMinutes+request.Duration > limits.MaxWeeklyMinutes {
+		return &Violation{Rule: RuleMaxWeeklyMinutes, Limit: limits.MaxWeeklyMinutes, Actual: usage.WeeklyMinutes + request.Duration}
+	}
+	return nil
+}
+
+// WeekRange 返回日期所在周的周一和周日
+func WeekRange(date time.Time) (time.Time, time.Time) {
+	offset := (int(date.Weekday()) + 6) % 7
+	monday := date.AddDate(0, 0, -offset)
+	return monday, monday.AddDate(0, 0, 6)
+}
@@ -2,12 +2,15 @@ package routes
 
 import (
 	"roomly/handlers"
+	"roomly/middleware"
+	"roomly/policy"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes() *gin.Engine {
+// SetupRoutes 注册所有路由，verifier 用于校验请求 token 并解析当前会员
+func SetupRoutes(verifier middleware.TokenVerifier) *gin.Engine {
 	r := gin.Default()
 
 	// 配置CORS
@@ -17,8 +20,9 @@ func SetupRoutes() *gin.Engine {
 	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
 	r.Use(cors.New(config))
 
-	// API路由组
+	// API路由组，所有接口均需登录
 	api := r.Group("/api")
+	api.Use(middleware.Auth(verifier))
 	{
 		// 给用户发信息相关路由
 		users := api.Group("/users")
@@ -31,15 +35,24 @@ func SetupRoutes() *gin.Engine {
 		// 会员相关路由
 		members := api.Group("/members")
 		{
-			members.GET("", handlers.GetMembers)
+			members.GET("/me", handlers.GetCurrentMember)
+			members.GET("/me/preferences", handlers.GetMyPreferences)
+			members.PUT("/me/preferences", handlers.UpdateMyPreferences)
+			members.GET("/me/preferences/channels", handlers.GetNotificationChannels)
 			members.GET("/:id", handlers.GetMember)
 			members.GET("/:id/dootask", handlers.GetMemberForDootaskId)
-			members.POST("", handlers.CreateMember)
-			members.PUT("/:id", handlers.UpdateMember)
-			members.DELETE("/:id", handlers.DeleteMember)
-			members.PUT("/:id/admin", handlers.SetAdminPermission)
-			members.PUT("/:id/room-admin", handlers.SetRoomAdminPermission)
 			members.GET("/:id/bookings", handlers.GetMemberBookings)
+			members.GET("/:id/no-shows", handlers.GetMemberNoShows)
+
+			// 仅管理员
+			adminMembers := members.Group("", middleware.RequireRole(policy.RoleAdmin))
+			adminMembers.GET("", handlers.GetMembers)
+			adminMembers.GET("/no-shows", handlers.GetNoShowStats)
+			adminMembers.POST("", handlers.CreateMember)
+			adminMembers.PUT("/:id", handlers.UpdateMember)
+			adminMembers.DELETE("/:id", handlers.DeleteMember)
+			adminMembers.PUT("/:id/admin", handlers.SetAdminPermission)
+			adminMembers.PUT("/:id/room-admin", handlers.SetRoomAdminPermission)
 		}
 
 		// 会议室相关路由
@@ -47,31 +60,149 @@ func SetupRoutes() *gin.Engine {
 		{
 			rooms.GET("", handlers.GetRooms)
 			rooms.GET("/open", handlers.GetOpenRooms)
+			rooms.GET("/search", handlers.SearchRooms)
+			rooms.GET("/grid", handlers.GetRoomGrid)
+			rooms.GET("/locations", handlers.GetRoomLocations)
 			rooms.GET("/:id", handlers.GetRoom)
-			rooms.POST("", handlers.CreateRoom)
-			rooms.PUT("/:id", handlers.UpdateRoom)
-			rooms.DELETE("/:id", handlers.DeleteRoom)
-			rooms.PUT("/:id/toggle", handlers.ToggleRoomStatus)
 			rooms.GET("/:id/bookings", handlers.GetRoomBookings)
+			rooms.GET("/:id/admins", handlers.GetRoomAdmins)
+			rooms.GET("/:id/blackouts", handlers.GetRoomBlackouts)
+
+			// 会议室管理员及以上，处理函数内校验是否管理该会议室
+			roomAdmin := rooms.Group("", middleware.RequireRole(policy.RoleRoomAdmin))
+			roomAdmin.PUT("/:id/toggle", handlers.ToggleRoomStatus)
+			roomAdmin.PUT("/:id/business-hours", handlers.UpdateRoomBusinessHours)
+			roomAdmin.POST("/:id/blackouts", handlers.CreateRoomBlackout)
+			roomAdmin.GET("/:id/blackouts/:blackout_id/conflicts", handlers.GetRoomBlackoutConflicts)
+			roomAdmin.POST("/:id/blackouts/:blackout_id/cancel-conflicts", handlers.CancelRoomBlackoutConflicts)
+			roomAdmin.DELETE("/:id/blackouts/:blackout_id", handlers.DeleteRoomBlackout)
+			roomAdmin.PUT("/:id/amenities", handlers.UpdateRoomAmenities)
+			roomAdmin.POST("/:id/photos", handlers.CreateRoomPhoto)
+			roomAdmin.PUT("/:id/photos/:photo_id", handlers.UpdateRoomPhoto)
+			roomAdmin.DELETE("/:id/photos/:photo_id", handlers.DeleteRoomPhoto)
+
+			// 仅管理员
+			adminRooms := rooms.Group("", middleware.RequireRole(policy.RoleAdmin))
+			adminRooms.POST("", handlers.CreateRoom)
+			adminRooms.PUT("/:id", handlers.UpdateRoom)
+			adminRooms.DELETE("/:id", handlers.DeleteRoom)
+			adminRooms.POST("/:id/admins", handlers.AddRoomAdmin)
+			adminRooms.DELETE("/:id/admins/:member_id", handlers.RemoveRoomAdmin)
+		}
+
+		// 会议室设施
+		amenities := api.Group("/amenities")
+		{
+			amenities.GET("", handlers.GetAmenities)
+
+			adminAmenities := amenities.Group("", middleware.RequireRole(policy.RoleAdmin))
+			adminAmenities.POST("", handlers.CreateAmenity)
+			adminAmenities.PUT("/:id", handlers.UpdateAmenity)
+			adminAmenities.DELETE("/:id", handlers.DeleteAmenity)
 		}
 
-		// 预定相关路由
+		// 预定相关路由，取消和修改由处理函数按预定人校验权限
 		bookings := api.Group("/bookings")
 		{
-			bookings.GET("", handlers.GetBookings)
 			bookings.POST("", handlers.CreateBooking)
+			bookings.PUT("/:id", handlers.UpdateBooking)
 			bookings.PUT("/:id/cancel", handlers.CancelBooking)
+			bookings.PUT("/:id/check-in", handlers.CheckInBooking)
+			bookings.GET("/:id/changes", handlers.GetBookingChanges)
+			bookings.GET("/:id/ics", handlers.ExportBookingICS)
 			bookings.GET("/available-slots", handlers.GetAvailableSlots)
+			bookings.GET("/busy", handlers.GetBusyTimes)
+			bookings.POST("/series", handlers.CreateBookingSeries)
+			bookings.GET("/series/:id", handlers.GetBookingSeries)
+			bookings.PUT("/:id/series", handlers.UpdateBookingSeries)
+
+			// 会议室管理员及以上
+			roomAdmin := bookings.Group("", middleware.RequireRole(policy.RoleRoomAdmin))
+			roomAdmin.GET("", handlers.GetBookings)
+			roomAdmin.GET("/pending", handlers.GetPendingBookings)
+			roomAdmin.PUT("/:id/approve", handlers.ApproveBooking)
+			roomAdmin.PUT("/:id/reject", handlers.RejectBooking)
 		}
 
-		// 导出相关路由
-		export := api.Group("/export")
+		// 候补
+		waitlist := api.Group("/waitlist")
+		{
+			waitlist.GET("", handlers.GetWaitlist)
+			waitlist.POST("", handlers.JoinWaitlist)
+			waitlist.PUT("/:id/withdraw", handlers.WithdrawWaitlist)
+			waitlist.POST("/:id/claim", handlers.ClaimWaitlist)
+		}
+
+		// 预定配额策略，仅管理员
+		quotas := api.Group("/quota-policies", middleware.RequireRole(policy.RoleAdmin))
+		{
+			quotas.GET("", handlers.GetQuotaPolicies)
+			quotas.POST("", handlers.CreateQuotaPolicy)
+			quotas.PUT("/:id", handlers.UpdateQuotaPolicy)
+			quotas.DELETE("/:id", handlers.DeleteQuotaPolicy)
+		}
+
+		// 通知发件箱，仅管理员
+		notifications := api.Group("/notifications", middleware.RequireRole(policy.RoleAdmin))
+		{
+			notifications.GET("", handlers.GetNotifications)
+			notifications.POST("/retry", handlers.RetryDeadNotifications)
+			notifications.POST("/:id/retry", handlers.RetryNotification)
+		}
+
+		// 通知消息模板，仅管理员
+		templates := api.Group("/message-templates", middleware.RequireRole(policy.RoleAdmin))
+		{
+			templates.GET("", handlers.GetMessageTemplates)
+			templates.POST("/preview", handlers.PreviewMessageTemplate)
+			templates.GET("/:event/:audience/:locale", handlers.GetMessageTemplate)
+			templates.PUT("/:event/:audience/:locale", handlers.UpdateMessageTemplate)
+			templates.DELETE("/:event/:audience/:locale", handlers.ResetMessageTemplate)
+		}
+
+		// 日历订阅，每个会员管理自己的订阅
+		calendarFeeds := api.Group("/calendar-feeds")
+		{
+			calendarFeeds.GET("", handlers.GetCalendarFeeds)
+			calendarFeeds.POST("", handlers.CreateCalendarFeed)
+			calendarFeeds.DELETE("/:id", handlers.DeleteCalendarFeed)
+		}
+
+		// 预定事件回调订阅，仅管理员
+		webhooks := api.Group("/webhooks", middleware.RequireRole(policy.RoleAdmin))
+		{
+			webhooks.GET("", handlers.GetWebhooks)
+			webhooks.GET("/events", handlers.GetWebhookEvents)
+			webhooks.POST("", handlers.CreateWebhook)
+			webhooks.PUT("/:id", handlers.UpdateWebhook)
+			webhooks.DELETE("/:id", handlers.DeleteWebhook)
+			webhooks.POST("/:id/test", handlers.TestWebhook)
+			webhooks.GET("/:id/deliveries", handlers.GetWebhookDeliveries)
+			webhooks.POST("/:id/deliveries/:delivery_id/retry", handlers.RetryWebhookDelivery)
+		}
+
+		// 节假日日历
+		calendar := api.Group("/calendar")
+		{
+			calendar.GET("", handlers.GetCalendarDays)
+
+			adminCalendar := calendar.Group("", middleware.RequireRole(policy.RoleAdmin))
+			adminCalendar.POST("/import", handlers.ImportCalendar)
+			adminCalendar.PUT("/:date", handlers.SetCalendarDay)
+			adminCalendar.DELETE("/:date", handlers.DeleteCalendarDay)
+		}
+
+		// 导出相关路由，会议室管理员及以上
+		export := api.Group("/export", middleware.RequireRole(policy.RoleRoomAdmin))
 		{
 			export.GET("/bookings", handlers.ExportBookings)
 			export.GET("/room-usage", handlers.ExportRoomUsage)
 		}
 	}
 
+	// 日历客户端订阅，凭订阅地址中的 token 访问，无需登录
+	r.GET("/feeds/:token/calendar.ics", handlers.GetCalendarFeedICS)
+
 	// 健康检查
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -0,0 +1,176 @@
+// Package scheduler 在后台按计划运行定时任务。所有任务在同一个 goroutine 中按注册顺序执行，
+// 同一时刻到期的任务不会并发运行；单个任务 panic 只记录日志，不影响其他任务和后续执行。
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Schedule 任务的运行计划
+type Schedule interface {
+	// Next 返回 after 之后的下一次运行时间
+	Next(after time.Time) time.Time
+}
+
+// Every 按固定间隔运行
+type Every time.Duration
+
+// Next 实现 Schedule
+func (e Every) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(e))
+}
+
+// Daily 每天在本地时间 Hour:Minute 运行
+type Daily struct {
+	Hour   int
+	Minute int
+}
+
+// Next 实现 Schedule
+func (d Daily) Next(after time.Time) time.Time {
+	next := time.Date(after.Year(), after.Month(), after.Day(), d.Hour, d.Minute, 0, 0, after.Location())
+	if !next.After(after) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Weekly 每周 Weekday 在本地时间 Hour:Minute 运行
+type Weekly struct {
+	Weekday time.Weekday
+	Hour    int
+	Minute  int
+}
+
+// Next 实现 Schedule
+func (w Weekly) Next(after time.Time) time.Time {
+	next := time.Date(after.Year(), after.Month(), after.Day(), w.Hour, w.Minute, 0, 0, after.Location())
+	next = next.AddDate(0, 0, (int(w.Weekday)-int(next.Weekday())+7)%7)
+	if !next.After(after) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next
+}
+
+// ParseDaily 解析 HH:MM 格式的每日运行时间
+func ParseDaily(value string) (Daily, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return Daily{}, fmt.Errorf("invalid time %q, use HH:MM", value)
+	}
+	return Daily{Hour: clock.Hour(), Minute: clock.Minute()}, nil
+}
+
+type job struct {
+	name     string
+	schedule Schedule
+	run      func()
+	next     time.Time
+}
+
+// Scheduler 定时任务运行器
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    []*job
+	stop    chan struct{}
+	done    chan struct{}
+	started bool
+	once    sync.Once
+}
+
+// New 创建定时任务运行器
+func New() *Scheduler {
+	return &Scheduler{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// Every 注册按固定间隔运行的任务，启动后立即运行一次
+func (s *Scheduler) Every(name string, interval time.Duration, run func()) {
+	s.add(&job{name: name, schedule: Every(interval), run: run, next: time.Now()})
+}
+
+// Add 注册按计划运行的任务，首次运行时间为计划中当前时间之后的下一次
+func (s *Scheduler) Add(name string, schedule Schedule, run func()) {
+	s.add(&job{name: name, schedule: schedule, run: run, next: schedule.Next(time.Now())})
+}
+
+func (s *Scheduler) add(j *job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		panic("scheduler: cannot add job " + j.name + " after Start")
+	}
+	s.jobs = append(s.jobs, j)
+}
+
+// Start 在后台开始运行任务
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	go s.loop()
+}
+
+// Stop 停止运行任务，等待正在运行的任务结束
+func (s *Scheduler) Stop() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+	s.mu.Lock()
+	started := s.started
+	s.mu.Unlock()
+	if started {
+		<-s.done
+	}
+}
+
+func (s *Scheduler) loop() {
+	defer close(s.done)
+	for {
+		now := time.Now()
+		for _, j := range s.jobs {
+			if j.next.After(now) {
+				continue
+			}
+			runJob(j)
+			j.next = j.schedule.Next(now)
+		}
+
+		if len(s.jobs) == 0 {
+			<-s.stop
+			return
+		}
+		next := s.jobs[0].next
+		for _, j := range s.jobs[1:] {
+			if j.next.Before(next) {
+				next = j.next
+			}
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// 运行单个任务，panic 时记录日志
+func runJob(j *job) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Printf("定时任务 %s 运行失败: %v", j.name, err)
+		}
+	}()
+	j.run()
+}
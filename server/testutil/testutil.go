@@ -0,0 +1,36 @@
+// Package testutil 提供各包测试共用的数据库和登录身份
+package testutil
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"roomly/database"
+	"roomly/middleware"
+)
+
+// OpenDB 在测试的临时目录中打开数据库并赋给 database.DB，测试结束后关闭
+func OpenDB(t *testing.T) {
+	t.Helper()
+	if err := database.Open(filepath.Join(t.TempDir(), "roomly.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+// Verifier 测试用的 token 校验：按 token 查找用户身份，不请求 DooTask
+type Verifier map[string]middleware.Identity
+
+// Verify 实现 middleware.TokenVerifier
+func (v Verifier) Verify(token string) (*middleware.Identity, error) {
+	identity, ok := v[token]
+	if !ok {
+		return nil, errors.New("invalid token")
+	}
+	return &identity, nil
+}
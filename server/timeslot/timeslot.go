@@ -0,0 +1,186 @@
+// Package timeslot 统一处理时间段的解析、生成、连续性与重叠判断。
+// 一天内的时间以分钟数表示，结束时间 24:00（或旧数据中的 00:00）统一视为 1440。
+package timeslot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DayMinutes 一天的分钟数
+	DayMinutes = 24 * 60
+	// UnitMinutes 时间段占用的最小单元，所有支持的时间段长度都是它的整数倍
+	UnitMinutes = 15
+	// DefaultLength 默认时间段长度
+	DefaultLength = 30
+)
+
+// SupportedLengths 会议室可配置的时间段长度（分钟）
+var SupportedLengths = []int{15, 30, 60}
+
+// ErrInvalidClock 时间格式错误
+var ErrInvalidClock = errors.New("invalid time, use HH:MM")
+
+// Window 半开区间 [Start, End)，单位为当天的分钟数
+type Window struct {
+	Start int
+	End   int
+}
+
+// IsSupportedLength 判断时间段长度是否受支持
+func IsSupportedLength(length int) bool {
+	for _, supported := range SupportedLengths {
+		if supported == length {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse 将 HH:MM 解析为分钟数，允许 24:00
+func Parse(clock string) (int, error) {
+	parts := strings.Split(clock, ":")
+	if len(parts) != 2 || len(parts[0]) != 2 || len(parts[1]) != 2 {
+		return 0, ErrInvalidClock
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, ErrInvalidClock
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute >= 60 || hour < 0 {
+		return 0, ErrInvalidClock
+	}
+	total := hour*60 + minute
+	if total > DayMinutes {
+		return 0, ErrInvalidClock
+	}
+	return total, nil
+}
+
+// Format 将分钟数格式化为 HH:MM，一天结束格式化为 24:00
+func Format(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// Add 返回 clock 之后 length 分钟的时间，不会跨过 24:00
+func Add(clock string, length int) string {
+	start, err := Parse(clock)
+	if err != nil {
+		return clock
+	}
+	end := start + length
+	if end > DayMinutes {
+		end = DayMinutes
+	}
+	return Format(end)
+}
+
+// ParseWindow 解析开始和结束时间，结束时间为 00:00 时表示到当天结束
+func ParseWindow(startClock, endClock string) (Window, error) {
+	start, err := Parse(startClock)
+	if err != nil {
+		return Window{}, err
+	}
+	end, err := Parse(endClock)
+	if err != nil {
+		return Window{}, err
+	}
+	if end == 0 && start > 0 {
+		end = DayMinutes
+	}
+	return Window{Start: start, End: end}, nil
+}
+
+// MustWindow 与 ParseWindow 相同，解析失败时返回空区间
+func MustWindow(startClock, endClock string) Window {
+	window, _ := ParseWindow(startClock, endClock)
+	return window
+}
+
+// Duration 区间时长（分钟）
+func (w Window) Duration() int {
+	return w.End - w.Start
+}
+
+// Overlaps 判断两个区间是否重叠
+func (w Window) Overlaps(other Window) bool {
+	return w.Start < other.End && w.End > other.Start
+}
+
+// Contains 判断区间是否完全包含另一个区间
+func (w Window) Contains(other Window) bool {
+	return w.Start <= other.Start && other.End <= w.End
+}
+
+// StartClock 开始时间 HH:MM
+func (w Window) StartClock() string {
+	return Format(w.Start)
+}
+
+// EndClock 结束时间 HH:MM，一天结束为 24:00
+func (w Window) EndClock() string {
+	return Format(w.End)
+}
+
+// Units 区间覆盖的占用单元的开始分钟
+func (w Window) Units() []int {
+	var units []int
+	for minute := w.Start - w.Start%UnitMinutes; minute < w.End; minute += UnitMinutes {
+		units = append(units, minute)
+	}
+	return units
+}
+
+// Generate 在营业时间 [open, close) 内按 length 生成时间段，末尾不足一个时间段的部分被忽略
+func Generate(open Window, length int) []Window {
+	if length <= 0 {
+		length = DefaultLength
+	}
+	var slots []Window
+	for start := open.Start; start+length <= open.End; start += length {
+		slots = append(slots, Window{Start: start, End: start + length})
+	}
+	return slots
+}
+
+// Span 将一组连续的时间段开始时间合并为一个区间，时间段不连续时返回错误
+func Span(starts []string, length int) (Window, error) {
+	if len(starts) == 0 {
+		return Window{}, errors.New("time slots are required")
+	}
+	first, err := Parse(starts[0])
+	if err != nil {
+		return Window{}, err
+	}
+	expected := first
+	for _, clock := range starts {
+		start, err := Parse(clock)
+		if err != nil {
+			return Window{}, err
+		}
+		if start != expected {
+			return Window{}, errors.New("time slots must be consecutive")
+		}
+		expected = start + length
+	}
+	if expected > DayMinutes {
+		return Window{}, errors.New("time slots must end by 24:00")
+	}
+	return Window{Start: first, End: expected}, nil
+}
+
+// Starts 将区间按 length 拆分为时间段开始时间列表
+func Starts(w Window, length int) []string {
+	if length <= 0 {
+		length = DefaultLength
+	}
+	var starts []string
+	for start := w.Start; start < w.End; start += length {
+		starts = append(starts, Format(start))
+	}
+	return starts
+}
@@ -0,0 +1,314 @@
+// Package webhook 向外部系统推送预定事件。预定变更时在同一事务中为每个匹配的订阅写入投递记录，
+// 提交后由投递任务 POST 完整的预定 JSON，请求头带 HMAC-SHA256 签名；失败时与通知发件箱一样按指数退避重试，
+// 超过最大尝试次数后标记为 dead，由管理员检查后手动重试。
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"roomly/database"
+	"roomly/models"
+	"roomly/outbox"
+
+	"gorm.io/gorm"
+)
+
+// 预定事件
+const (
+	EventCreated   = "booking.created"
+	EventUpdated   = "booking.updated"
+	EventCancelled = "booking.cancelled"
+	EventExpired   = "booking.expired"
+	EventNoShow    = "booking.no_show"
+	// EventPing 测试事件，不受订阅的事件筛选限制
+	EventPing = "ping"
+)
+
+// Events 可以订阅的预定事件
+var Events = []string{EventCreated, EventUpdated, EventCancelled, EventExpired, EventNoShow}
+
+// 请求头
+const (
+	HeaderEvent     = "X-Roomly-Event"
+	HeaderDelivery  = "X-Roomly-Delivery"
+	HeaderSignature = "X-Roomly-Signature"
+)
+
+// 投递中的记录在该时间内不会被其他投递任务领取
+const leaseDuration = 2 * time.Minute
+
+// 每次投递最多处理的记录数
+const batchSize = 100
+
+// 记录的响应内容最大长度
+const maxResponseBody = 1024
+
+// Client 发送回调请求使用的 HTTP 客户端
+var Client = &http.Client{Timeout: 10 * time.Second}
+
+// Payload 回调请求体
+type Payload struct {
+	Event      string         `json:"event"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	Booking    models.Booking `json:"booking"`
+}
+
+// IsEvent 判断是否为可以订阅的预定事件
+func IsEvent(event string) bool {
+	for _, known := range Events {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscribes 判断订阅是否接收该事件
+func Subscribes(subscription *models.WebhookSubscription, event string) bool {
+	if event == EventPing || strings.TrimSpace(subscription.Events) == "" {
+		return true
+	}
+	for _, subscribed := range strings.Split(subscription.Events, ",") {
+		if strings.TrimSpace(subscribed) == event {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSecret 生成随机的签名密钥
+func NewSecret() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// Sign 请求体的签名，格式为 sha256=<十六进制 HMAC>
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Publish 在事务 tx 中发布预定事件：为每个订阅了该事件的启用订阅写入一条待投递记录，
+// 请求体包含事务中当前的预定及其会议室、预定人和参会人员
+func Publish(tx *gorm.DB, event string, bookingIDs ...uint) error {
+	if len(bookingIDs) == 0 {
+		return nil
+	}
+	var subscriptions []models.WebhookSubscription
+	if err := tx.Where("is_active = ?", true).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+	var matched []models.WebhookSubscription
+	for _, subscription := range subscriptions {
+		if Subscribes(&subscription, event) {
+			matched = append(matched, subscription)
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+
+	var bookings []models.Booking
+	if err := tx.Preload("Room").Preload("Member").Preload("BookingUsers").
+		Where("id IN ?", bookingIDs).Order("id asc").Find(&bookings).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	var deliveries []models.WebhookDelivery
+	for _, booking := range bookings {
+		data, err := json.Marshal(Payload{Event: event, OccurredAt: now, Booking: booking})
+		if err != nil {
+			return err
+		}
+		for _, subscription := range matched {
+			deliveries = append(deliveries, models.WebhookDelivery{
+				SubscriptionID: subscription.ID,
+				Event:          event,
+				BookingID:      booking.ID,
+				Payload:        string(data),
+				Status:         outbox.StatusPending,
+				NextAttemptAt:  now,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return tx.Create(&deliveries).Error
+}
+
+// Ping 给订阅写入一条使用示例预定的测试事件并立即投递，返回投递结果
+func Ping(subscription *models.WebhookSubscription) (*models.WebhookDelivery, error) {
+	now := time.Now()
+	data, err := json.Marshal(Payload{Event: EventPing, OccurredAt: now, Booking: sampleBooking(now)})
+	if err != nil {
+		return nil, err
+	}
+	delivery := models.WebhookDelivery{
+		SubscriptionID: subscription.ID,
+		Event:          EventPing,
+		Payload:        string(data),
+		Status:         outbox.StatusPending,
+		NextAttemptAt:  now,
+	}
+	if err := database.DB.Create(&delivery).Error; err != nil {
+		return nil, err
+	}
+	if claim(&delivery, now) {
+		attempt(&delivery, subscription)
+	}
+	return &delivery, nil
+}
+
+// Deliver 投递所有到期的待投递记录；先领取再发送，可以与其他投递任务并发运行
+func Deliver() {
+	now := time.Now()
+	var due []models.WebhookDelivery
+	if err := database.DB.Where("status = ? AND next_attempt_at <= ?", outbox.StatusPending, now).
+		Order("id asc").Limit(batchSize).Find(&due).Error; err != nil {
+		fmt.Printf("加载待投递的回调失败: %v\n", err)
+		return
+	}
+
+	for i := range due {
+		delivery := &due[i]
+		if !claim(delivery, now) {
+			continue
+		}
+		var subscription models.WebhookSubscription
+		if err := database.DB.First(&subscription, delivery.SubscriptionID).Error; err != nil {
+			database.DB.Model(delivery).Updates(map[string]interface{}{"status": outbox.StatusDead, "last_error": "Subscription not found"})
+			continue
+		}
+		attempt(delivery, &subscription)
+	}
+}
+
+// Retry 将投递记录重新置为待投递并清零尝试次数，已投递成功的记录不能重试
+func Retry(db *gorm.DB, delivery *models.WebhookDelivery) error {
+	if delivery.Status == outbox.StatusSent {
+		return errors.New("Delivery has already succeeded")
+	}
+	delivery.Status = outbox.StatusPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	return db.Model(delivery).Updates(map[string]interface{}{
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"next_attempt_at": delivery.NextAttemptAt,
+	}).Error
+}
+
+// PruneDelivered 删除投递时间早于 before 的成功投递记录
+func PruneDelivered(before time.Time) {
+	database.DB.Where("status = ? AND delivered_at < ?", outbox.StatusSent, before).Delete(&models.WebhookDelivery{})
+}
+
+// 领取投递记录：推迟下次尝试时间，其他投递任务不会重复发送
+func claim(delivery *models.WebhookDelivery, now time.Time) bool {
+	result := database.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, outbox.StatusPending, now).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": time.Now().Add(leaseDuration),
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+	delivery.Attempts++
+	return true
+}
+
+// 发送一次回调请求并记录结果，失败时按退避间隔安排重试
+func attempt(delivery *models.WebhookDelivery, subscription *models.WebhookSubscription) {
+	status, body, err := post(delivery, subscription)
+	delivery.ResponseStatus = status
+	delivery.ResponseBody = body
+	updates := map[string]interface{}{
+		"response_status": status,
+		"response_body":   body,
+	}
+	if err != nil {
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= outbox.MaxAttempts {
+			delivery.Status = outbox.StatusDead
+			updates["status"] = delivery.Status
+		} else {
+			delivery.NextAttemptAt = time.Now().Add(outbox.Backoff(delivery.Attempts))
+			updates["next_attempt_at"] = delivery.NextAttemptAt
+		}
+		updates["last_error"] = delivery.LastError
+		database.DB.Model(delivery).Updates(updates)
+		fmt.Printf("投递回调%d到订阅%d失败（第%d次）: %v\n", delivery.ID, subscription.ID, delivery.Attempts, err)
+		return
+	}
+
+	deliveredAt := time.Now()
+	delivery.Status = outbox.StatusSent
+	delivery.DeliveredAt = &deliveredAt
+	delivery.LastError = ""
+	updates["status"] = delivery.Status
+	updates["delivered_at"] = deliveredAt
+	updates["last_error"] = ""
+	database.DB.Model(delivery).Updates(updates)
+}
+
+// 发送回调请求，返回响应状态码和截取后的响应内容；非 2xx 状态码视为失败
+func post(delivery *models.WebhookDelivery, subscription *models.WebhookSubscription) (int, string, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Roomly-Webhook")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, body))
+
+	resp, err := Client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(data), fmt.Errorf("Webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(data), nil
+}
+
+// 测试事件使用的示例预定
+func sampleBooking(now time.Time) models.Booking {
+	return models.Booking{
+		Date:      now.Format("2006-01-02"),
+		StartTime: "10:00",
+		EndTime:   "11:00",
+		Reason:    "项目周会",
+		Status:    "active",
+		CreatedAt: now,
+		UpdatedAt: now,
+		Room:      models.Room{Name: "多功能会议室A"},
+		Member:    models.Member{Name: "张三"},
+		BookingUsers: []models.BookingUser{
+			{Userid: 1, Nickname: "张三"},
+			{Userid: 2, Nickname: "李四"},
+		},
+	}
+}
@@ -0,0 +1,157 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"roomly/database"
+	"roomly/models"
+	"roomly/outbox"
+	"roomly/testutil"
+)
+
+// 回调接收方收到的请求
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// 启动回调接收方，按 status 响应，收到的请求写入返回的 channel
+func startReceiver(t *testing.T, status int) (*httptest.Server, <-chan received) {
+	t.Helper()
+	requests := make(chan received, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header.Clone(), body: body}
+		w.WriteHeader(status)
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+// 创建一个预定和订阅了 events 的回调订阅
+func createFixtures(t *testing.T, url, events string) (*models.Booking, *models.WebhookSubscription) {
+	t.Helper()
+	var room models.Room
+	if err := database.DB.First(&room).Error; err != nil {
+		t.Fatal(err)
+	}
+	member := models.Member{DootaskID: 1, Name: "alice"}
+	if err := database.DB.Create(&member).Error; err != nil {
+		t.Fatal(err)
+	}
+	booking := models.Booking{RoomID: room.ID, MemberID: member.ID, Date: "2025-01-06", StartTime: "10:00", EndTime: "11:00", Reason: "项目周会", Status: "active"}
+	if err := database.DB.Create(&booking).Error; err != nil {
+		t.Fatal(err)
+	}
+	subscription := models.WebhookSubscription{Name: "test", URL: url, Events: events, Secret: NewSecret(), IsActive: true}
+	if err := database.DB.Create(&subscription).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &booking, &subscription
+}
+
+// 接收方按文档校验签名：请求体的 HMAC-SHA256
+func validSignature(secret string, body []byte, signature string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+func TestDeliverSignsPayload(t *testing.T) {
+	testutil.OpenDB(t)
+	server, requests := startReceiver(t, http.StatusOK)
+	booking, subscription := createFixtures(t, server.URL, EventCreated+","+EventCancelled)
+
+	if err := Publish(database.DB, EventCreated, booking.ID); err != nil {
+		t.Fatal(err)
+	}
+	Deliver()
+
+	var got received
+	select {
+	case got = <-requests:
+	default:
+		t.Fatal("webhook was not delivered")
+	}
+	if !validSignature(subscription.Secret, got.body, got.header.Get(HeaderSignature)) {
+		t.Errorf("invalid signature %q", got.header.Get(HeaderSignature))
+	}
+	if validSignature("other secret", got.body, got.header.Get(HeaderSignature)) {
+		t.Error("signature verifies with another secret")
+	}
+	if event := got.header.Get(HeaderEvent); event != EventCreated {
+		t.Errorf("%s = %q, want %q", HeaderEvent, event, EventCreated)
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(got.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Event != EventCreated || payload.Booking.ID != booking.ID || payload.Booking.Member.Name != "alice" {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	var delivery models.WebhookDelivery
+	database.DB.First(&delivery)
+	if delivery.Status != outbox.StatusSent || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusOK {
+		t.Errorf("delivery = %+v, want sent after 1 attempt", delivery)
+	}
+}
+
+func TestPublishSkipsUnsubscribedEvents(t *testing.T) {
+	testutil.OpenDB(t)
+	server, _ := startReceiver(t, http.StatusOK)
+	booking, _ := createFixtures(t, server.URL, EventCancelled)
+
+	if err := Publish(database.DB, EventCreated, booking.ID); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	database.DB.Model(&models.WebhookDelivery{}).Count(&count)
+	if count != 0 {
+		t.Errorf("published %d deliveries for an unsubscribed event", count)
+	}
+}
+
+func TestDeliverRetriesFailedRequests(t *testing.T) {
+	testutil.OpenDB(t)
+	server, requests := startReceiver(t, http.StatusInternalServerError)
+	booking, _ := createFixtures(t, server.URL, "")
+
+	if err := Publish(database.DB, EventCancelled, booking.ID); err != nil {
+		t.Fatal(err)
+	}
+	Deliver()
+	if len(requests) != 1 {
+		t.Fatalf("sent %d requests, want 1", len(requests))
+	}
+
+	var delivery models.WebhookDelivery
+	database.DB.First(&delivery)
+	if delivery.Status != outbox.StatusPending || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusInternalServerError {
+		t.Errorf("delivery = %+v, want pending after 1 attempt", delivery)
+	}
+	if !strings.Contains(delivery.LastError, "500") {
+		t.Errorf("last error = %q", delivery.LastError)
+	}
+	if !delivery.NextAttemptAt.After(time.Now()) {
+		t.Error("retry is not scheduled in the future")
+	}
+
+	// 未到重试时间时不再发送
+	Deliver()
+	if len(requests) != 1 {
+		t.Errorf("sent %d requests before the retry was due", len(requests))
+	}
+}